@@ -0,0 +1,149 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/Eun/goremovelines"
+)
+
+const cacheDirPerm = 0o755
+
+// cleanCache remembers sources that are already clean.
+// Every clean source is stored as an empty file, named by the hash of the tool version, the mode and the content.
+// A nil cache remembers nothing.
+type cleanCache struct {
+	dir string
+}
+
+// cacheDir returns the directory goremovelines keeps its cache in.
+// It can be overwritten with the GOREMOVELINES_CACHE_DIR environment variable.
+func cacheDir() (string, error) {
+	if dir := os.Getenv("GOREMOVELINES_CACHE_DIR"); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to determine cache directory: %w", err)
+	}
+	return filepath.Join(dir, "goremovelines"), nil
+}
+
+func newCleanCache() (*cleanCache, error) {
+	if toolVersion() == "" {
+		debugf("cache disabled: unable to determine version")
+		return nil, nil
+	}
+	dir, err := cacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return &cleanCache{dir: filepath.Join(dir, "clean")}, nil
+}
+
+func clearCache() error {
+	dir, err := cacheDir()
+	if err != nil {
+		return err
+	}
+	debugf("clearing cache %s", dir)
+	return os.RemoveAll(dir)
+}
+
+// key returns the cache key of src, it contains all options that change the result and the commands of the pipeline
+// with the paths, sizes and modification times of their executables and scripts.
+// The key is empty (nothing is cached) if the executable of a command can not be found.
+func (c *cleanCache) key(src []byte, opts goremovelines.Options, pipeline ...string) string {
+	if c == nil {
+		return ""
+	}
+	h := sha256.New()
+	_, _ = io.WriteString(h, toolVersion()+"\x00"+strconv.Itoa(int(opts.Mode))+"\x00"+strconv.FormatBool(opts.Tolerant)+"\x00"+
+		strconv.FormatBool(opts.Fragment)+"\x00"+strconv.FormatBool(opts.Gofmt)+"\x00"+
+		strconv.FormatBool(opts.Verify)+"\x00")
+	for _, step := range pipeline {
+		_, _ = io.WriteString(h, step+"\x00")
+		if step == cleanStep {
+			continue
+		}
+		files, err := stepFiles(step)
+		if err != nil {
+			debugf("not caching: %v", err)
+			return ""
+		}
+		for _, file := range files {
+			fi, err := os.Stat(file)
+			if err != nil {
+				debugf("not caching: %v", err)
+				return ""
+			}
+			_, _ = fmt.Fprintf(h, "%s\x00%d\x00%d\x00", file, fi.Size(), fi.ModTime().UnixNano())
+		}
+	}
+	_, _ = h.Write(src)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *cleanCache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key)
+}
+
+func (c *cleanCache) isClean(key string) bool {
+	if c == nil || key == "" {
+		return false
+	}
+	_, err := os.Stat(c.path(key))
+	return err == nil
+}
+
+func (c *cleanCache) markClean(key string) error {
+	if c == nil || key == "" {
+		return nil
+	}
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), cacheDirPerm); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+var (
+	toolVersionOnce  sync.Once
+	toolVersionValue string
+)
+
+// toolVersion returns the version that is part of the cache keys, it is empty if the version is unknown.
+func toolVersion() string {
+	toolVersionOnce.Do(func() {
+		if version != "" {
+			toolVersionValue = version + " " + commit
+			return
+		}
+		// development builds have no version, use the hash of the binary instead
+		exe, err := os.Executable()
+		if err != nil {
+			return
+		}
+		f, err := os.Open(exe)
+		if err != nil {
+			return
+		}
+		defer f.Close()
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return
+		}
+		toolVersionValue = hex.EncodeToString(h.Sum(nil))
+	})
+	return toolVersionValue
+}
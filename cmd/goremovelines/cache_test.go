@@ -0,0 +1,132 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/Eun/goremovelines"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanCache(t *testing.T) {
+	t.Setenv("GOREMOVELINES_CACHE_DIR", t.TempDir())
+	c, err := newCleanCache()
+	require.NoError(t, err)
+	require.NotNil(t, c)
+
+	src := []byte("package a\n")
+	opts := goremovelines.Options{Mode: goremovelines.AllMode}
+	key := c.key(src, opts)
+	require.Len(t, key, 64)
+	require.Equal(t, key, c.key(src, opts), "keys are stable")
+
+	// every option that changes the result and the source are part of the key
+	other := []string{
+		c.key([]byte("package b\n"), opts),
+		c.key(src, goremovelines.Options{Mode: goremovelines.FuncMode}),
+		c.key(src, goremovelines.Options{Mode: goremovelines.AllMode, Tolerant: true}),
+		c.key(src, goremovelines.Options{Mode: goremovelines.AllMode, Fragment: true}),
+		c.key(src, goremovelines.Options{Mode: goremovelines.AllMode, Gofmt: true}),
+		c.key(src, goremovelines.Options{Mode: goremovelines.AllMode, Verify: true}),
+		c.key(src, opts, "cat"),
+		c.key(src, opts, "cat", cleanStep),
+	}
+	seen := map[string]bool{key: true}
+	for i, k := range other {
+		require.False(t, seen[k], "Test %d failed", i)
+		seen[k] = true
+	}
+
+	require.False(t, c.isClean(key))
+	require.NoError(t, c.markClean(key))
+	require.True(t, c.isClean(key))
+	require.False(t, c.isClean(other[0]))
+
+	require.NoError(t, clearCache())
+	require.False(t, c.isClean(key))
+
+	// a nil cache remembers nothing
+	var nilCache *cleanCache
+	require.Empty(t, nilCache.key(src, opts))
+	require.NoError(t, nilCache.markClean(key))
+	require.False(t, nilCache.isClean(key))
+}
+
+func TestCleanCachePipeline(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("stub scripts need a shell")
+	}
+	t.Setenv("GOREMOVELINES_CACHE_DIR", t.TempDir())
+	c, err := newCleanCache()
+	require.NoError(t, err)
+	src := []byte("package a\n")
+	opts := goremovelines.Options{Mode: goremovelines.AllMode}
+
+	// commands that can not be found are not cached
+	require.Empty(t, c.key(src, opts, "does-not-exist"))
+	require.NoError(t, c.markClean(""))
+	require.False(t, c.isClean(""))
+
+	// changes of the executable and of scripts in the arguments change the key
+	exe := writeScript(t, "fmt", "cat")
+	script := writeScript(t, "fmt.sh", "cat")
+	key := c.key(src, opts, exe)
+	scriptKey := c.key(src, opts, "sh "+script)
+	require.NotEmpty(t, key)
+	require.NotEmpty(t, scriptKey)
+	require.Equal(t, key, c.key(src, opts, exe))
+
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.WriteFile(exe, []byte("#!/bin/sh\ncat; echo\n"), 0o700)) //nolint:gosec // test script
+	require.NoError(t, os.Chtimes(exe, later, later))
+	require.NotEqual(t, key, c.key(src, opts, exe))
+	require.NoError(t, os.Chtimes(script, later, later))
+	require.NotEqual(t, scriptKey, c.key(src, opts, "sh "+script))
+}
+
+func TestRunCleanCache(t *testing.T) {
+	t.Setenv("GOREMOVELINES_CACHE_DIR", t.TempDir())
+	t.Setenv("GOREMOVELINES_STATE_DIR", t.TempDir())
+	setFlag(t, removeLineFlag, []string{"all"})
+	setFlag(t, cacheFlag, true)
+	setFlag(t, writeToSourceFlag, true)
+
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.go")
+	require.NoError(t, os.WriteFile(a, []byte("package a\nfunc a() {\n\n}\n"), 0o600))
+	require.NoError(t, cleanPaths([]string{a}, nil))
+	requireContent(t, a, "package a\nfunc a() {\n}\n")
+
+	// only clean sources are cached
+	r, err := newRun(nil)
+	require.NoError(t, err)
+	s, err := (*configLoader)(nil).settingsFor(dir)
+	require.NoError(t, err)
+	opts := goremovelines.Options{Mode: s.mode, Filename: a}
+	require.False(t, r.cache.isClean(r.cache.key([]byte("package a\nfunc a() {\n\n}\n"), opts)))
+	require.NoError(t, cleanPaths([]string{a}, nil))
+	require.True(t, r.cache.isClean(r.cache.key([]byte("package a\nfunc a() {\n}\n"), opts)))
+
+	// a changed pipeline step is run again for files that are known to be clean
+	script := writeScript(t, "fmt.sh", "cat")
+	setFlag(t, pipeFlag, []string{"sh " + script})
+	require.NoError(t, cleanPaths([]string{a}, nil))
+	requireContent(t, a, "package a\nfunc a() {\n}\n")
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.WriteFile(script, []byte("cat; echo '// formatted'"), 0o600))
+	require.NoError(t, os.Chtimes(script, later, later))
+	require.NoError(t, cleanPaths([]string{a}, nil))
+	requireContent(t, a, "package a\nfunc a() {\n}\n// formatted\n")
+	setFlag(t, pipeFlag, nil)
+
+	// files that are known to be clean are not rewritten
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	require.NoError(t, os.Chtimes(a, past, past))
+	require.NoError(t, cleanPaths([]string{a}, nil))
+	info, err := os.Stat(a)
+	require.NoError(t, err)
+	require.Equal(t, past, info.ModTime())
+}
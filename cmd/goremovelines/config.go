@@ -0,0 +1,252 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Eun/goremovelines"
+	"github.com/Eun/goremovelines/walker"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	configFileName = ".goremovelines.yaml"
+	// tomlConfigFileName is rejected, only YAML config files are supported.
+	tomlConfigFileName = ".goremovelines.toml"
+)
+
+// config is the content of a .goremovelines.yaml file.
+// Fields that are not set in a file are inherited from the config files in the parent directories.
+type config struct {
+	// Remove is the list of modes to clean (func, struct, if, ..., all).
+	Remove []string `yaml:"remove"`
+	// Keep is the list of modes that are not cleaned, even if they are part of Remove.
+	Keep []string `yaml:"keep"`
+	// Skip is the list of directory names to skip when expanding '...'.
+	Skip []string `yaml:"skip"`
+	// IncludeGenerated enables cleaning of generated files.
+	IncludeGenerated *bool `yaml:"include-generated"`
+	// Include is the list of glob patterns (relative to the config file) of files to clean.
+	Include []string `yaml:"include"`
+	// Exclude is the list of glob patterns (relative to the config file) of files and directories to skip.
+	Exclude []string `yaml:"exclude"`
+	// Format is the output format if the result is written to stdout (raw, txtar or json).
+	Format string `yaml:"format"`
+	// SkipHidden skips files and directories starting with `_' or `.' when expanding '...'.
+	SkipHidden *bool `yaml:"skip-hidden"`
+	// Pipe is the list of commands that run before and after the cleaning, see --pipe.
+	Pipe []string `yaml:"pipe"`
+	// PipeTimeout is the time a single pipe command may take (e.g. 10s).
+	PipeTimeout string `yaml:"pipe-timeout"`
+
+	// includeBase and excludeBase are the directories the patterns are relative to.
+	includeBase string
+	excludeBase string
+	// removeSource and keepSource are the paths of the config files that set Remove and Keep.
+	removeSource string
+	keepSource   string
+}
+
+// merge returns a copy of c where all fields that are set in child are replaced.
+func (c config) merge(child *config) config {
+	if child.Remove != nil {
+		c.Remove = child.Remove
+		c.removeSource = child.removeSource
+	}
+	if child.Keep != nil {
+		c.Keep = child.Keep
+		c.keepSource = child.keepSource
+	}
+	if child.Skip != nil {
+		c.Skip = child.Skip
+	}
+	if child.IncludeGenerated != nil {
+		c.IncludeGenerated = child.IncludeGenerated
+	}
+	if child.Include != nil {
+		c.Include = child.Include
+		c.includeBase = child.includeBase
+	}
+	if child.Exclude != nil {
+		c.Exclude = child.Exclude
+		c.excludeBase = child.excludeBase
+	}
+	if child.SkipHidden != nil {
+		c.SkipHidden = child.SkipHidden
+	}
+	if child.Format != "" {
+		c.Format = child.Format
+	}
+	if child.Pipe != nil {
+		c.Pipe = child.Pipe
+	}
+	if child.PipeTimeout != "" {
+		c.PipeTimeout = child.PipeTimeout
+	}
+	return c
+}
+
+// settings are the effective settings for the files in a directory.
+type settings struct {
+	mode             goremovelines.Mode
+	includeGenerated bool
+	filter           walker.Filter
+	format           string
+	pipe             pipeline
+	// modeSources are the paths of the config files that set the mode, it is empty if the mode is set by the flags.
+	modeSources []string
+}
+
+// settingsFor returns the settings for the files in dir, the patterns of the flags are relative to the walked root.
+// Flags that were set by the user take precedence over the config files, a nil loader only uses the flags.
+func (l *configLoader) settingsFor(dir string) (settings, error) {
+	mode, err := parseMode(*removeLineFlag, *keepFlag)
+	if err != nil {
+		return settings{}, err
+	}
+	s := settings{
+		mode:             mode,
+		includeGenerated: *includeGeneratedFlag,
+		filter: walker.Filter{
+			Skip:       *skipFlag,
+			SkipHidden: *skipHiddenFlag,
+			Include:    walker.NewPatterns("", *includeFlag),
+			Exclude:    walker.NewPatterns("", *excludeFlag),
+		},
+		format: *formatFlag,
+		pipe: pipeline{
+			steps:   *pipeFlag,
+			timeout: *pipeTimeoutFlag,
+		},
+	}
+	if l != nil {
+		c, err := l.load(dir)
+		if err != nil {
+			return settings{}, err
+		}
+		if (c.Remove != nil || c.Keep != nil) && !removeLineFlagSet && !keepFlagSet {
+			remove := c.Remove
+			if remove == nil {
+				remove = *removeLineFlag
+			}
+			s.mode, err = parseMode(remove, c.Keep)
+			if err != nil {
+				return settings{}, err
+			}
+			for _, source := range []string{c.removeSource, c.keepSource} {
+				if source != "" && !containsString(s.modeSources, source) {
+					s.modeSources = append(s.modeSources, source)
+				}
+			}
+		}
+		if c.Skip != nil && !skipFlagSet {
+			s.filter.Skip = c.Skip
+		}
+		if c.IncludeGenerated != nil && !includeGeneratedFlagSet {
+			s.includeGenerated = *c.IncludeGenerated
+		}
+		if c.Include != nil && !includeFlagSet {
+			s.filter.Include = walker.NewPatterns(c.includeBase, c.Include)
+		}
+		if c.Exclude != nil && !excludeFlagSet {
+			s.filter.Exclude = walker.NewPatterns(c.excludeBase, c.Exclude)
+		}
+		if c.SkipHidden != nil && !skipHiddenFlagSet {
+			s.filter.SkipHidden = *c.SkipHidden
+		}
+		if c.Format != "" && !formatFlagSet {
+			s.format = c.Format
+		}
+		if c.Pipe != nil && !pipeFlagSet {
+			s.pipe.steps = c.Pipe
+		}
+		if c.PipeTimeout != "" && !pipeTimeoutFlagSet {
+			// the timeout was validated when the config was read
+			s.pipe.timeout, _ = time.ParseDuration(c.PipeTimeout)
+		}
+	}
+	return s, nil
+}
+
+// configLoader looks up the config for a directory by walking up to the root directory.
+type configLoader struct {
+	cache map[string]config
+}
+
+func newConfigLoader() *configLoader {
+	return &configLoader{cache: make(map[string]config)}
+}
+
+// load returns the merged config of dir and all its parent directories.
+func (l *configLoader) load(dir string) (config, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return config{}, fmt.Errorf("unable to resolve `%s': %w", dir, err)
+	}
+	if c, ok := l.cache[dir]; ok {
+		return c, nil
+	}
+
+	var c config
+	if parent := filepath.Dir(dir); parent != dir {
+		c, err = l.load(parent)
+		if err != nil {
+			return config{}, err
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, tomlConfigFileName)); err == nil {
+		return config{}, fmt.Errorf("unable to read config `%s': TOML is not supported, use %s instead",
+			filepath.Join(dir, tomlConfigFileName), configFileName)
+	}
+	path := filepath.Join(dir, configFileName)
+	child, err := readConfig(path)
+	if err != nil {
+		return config{}, err
+	}
+	if child != nil {
+		debugf("using config %s", path)
+		c = c.merge(child)
+	}
+	l.cache[dir] = c
+	return c, nil
+}
+
+func readConfig(path string) (*config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to open config `%s': %w", path, err)
+	}
+	defer f.Close()
+
+	c := config{
+		includeBase:  filepath.Dir(path),
+		excludeBase:  filepath.Dir(path),
+		removeSource: path,
+		keepSource:   path,
+	}
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unable to parse config `%s': %w", path, err)
+	}
+	if c.Format != "" && !isOutputFormat(c.Format) {
+		return nil, fmt.Errorf("unable to parse config `%s': unknown format `%s'", path, c.Format)
+	}
+	if c.PipeTimeout != "" {
+		if _, err := time.ParseDuration(c.PipeTimeout); err != nil {
+			return nil, fmt.Errorf("unable to parse config `%s': invalid pipe-timeout: %w", path, err)
+		}
+	}
+	if _, err := parseMode(c.Remove, c.Keep); err != nil {
+		return nil, fmt.Errorf("unable to parse config `%s': %w", path, err)
+	}
+	return &c, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Eun/goremovelines"
+	"github.com/Eun/goremovelines/walker"
+	"github.com/stretchr/testify/require"
+)
+
+// writeConfig writes a config file with the content to dir.
+func writeConfig(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(dir, 0o700))
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestSettingsFor(t *testing.T) {
+	remove := *removeLineFlag
+	*removeLineFlag = []string{"all"}
+	defer func() {
+		*removeLineFlag = remove
+	}()
+
+	root := t.TempDir()
+	sub := filepath.Join(root, "sub")
+	deeper := filepath.Join(sub, "deeper")
+	require.NoError(t, os.MkdirAll(deeper, 0o700))
+	rootConfig := writeConfig(t, root, configFileName, `remove: [if, func]
+skip: [testdata]
+include: ["**/*.go"]
+exclude: ["gen/**"]
+format: json
+`)
+	subConfig := writeConfig(t, sub, configFileName, `keep: [if]
+exclude: ["*_mock.go"]
+include-generated: true
+`)
+
+	// without configs the flags are used
+	s, err := (*configLoader)(nil).settingsFor(deeper)
+	require.NoError(t, err)
+	require.Equal(t, goremovelines.AllMode, s.mode)
+	require.Empty(t, s.modeSources)
+
+	// the config is found in the parent directory
+	s, err = newConfigLoader().settingsFor(root)
+	require.NoError(t, err)
+	require.Equal(t, goremovelines.IfMode|goremovelines.FuncMode, s.mode)
+	require.Equal(t, []string{rootConfig}, s.modeSources)
+	require.Equal(t, []string{"testdata"}, s.filter.Skip)
+	require.Equal(t, "json", s.format)
+	require.False(t, s.includeGenerated)
+
+	// settings of a subdirectory override the settings of its parents, patterns are relative to their config
+	s, err = newConfigLoader().settingsFor(deeper)
+	require.NoError(t, err)
+	require.Equal(t, goremovelines.FuncMode, s.mode)
+	require.Equal(t, []string{rootConfig, subConfig}, s.modeSources)
+	require.Equal(t, []string{"testdata"}, s.filter.Skip)
+	require.True(t, s.includeGenerated)
+	require.Equal(t, []walker.Pattern{{Dir: root, Glob: "**/*.go"}}, s.filter.Include)
+	require.Equal(t, []walker.Pattern{{Dir: sub, Glob: "*_mock.go"}}, s.filter.Exclude)
+
+	// flags that were set by the user take precedence over the configs
+	removeLineFlagSet, excludeFlagSet = true, true
+	exclude := *excludeFlag
+	*removeLineFlag = []string{"struct"}
+	*excludeFlag = []string{"*.pb.go"}
+	defer func() {
+		removeLineFlagSet, excludeFlagSet = false, false
+		*excludeFlag = exclude
+	}()
+	s, err = newConfigLoader().settingsFor(deeper)
+	require.NoError(t, err)
+	require.Equal(t, goremovelines.StructMode, s.mode)
+	require.Empty(t, s.modeSources)
+	require.Equal(t, []walker.Pattern{{Glob: "*.pb.go"}}, s.filter.Exclude)
+	require.True(t, s.includeGenerated)
+}
+
+func TestReadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			configFileName, "nope: true\n",
+			"unable to parse config `%s': yaml: unmarshal errors:\n  line 1: field nope not found in type main.config",
+		},
+		{configFileName, "remove: [nope]\n", "unable to parse config `%s': unknown mode `nope'"},
+		{configFileName, "format: nope\n", "unable to parse config `%s': unknown format `nope'"},
+		{configFileName, "pipe-timeout: nope\n", "unable to parse config `%s': invalid pipe-timeout: time: invalid duration \"nope\""},
+		{tomlConfigFileName, "remove = [\"all\"]\n", "unable to read config `%s': TOML is not supported, use .goremovelines.yaml instead"},
+	}
+
+	for i, test := range tests {
+		path := writeConfig(t, t.TempDir(), test.name, test.content)
+		_, err := newConfigLoader().settingsFor(filepath.Dir(path))
+		require.EqualError(t, err, fmt.Sprintf(test.expected, path), "Test %d failed", i)
+	}
+}
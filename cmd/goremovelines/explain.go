@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Eun/goremovelines"
+)
+
+// parsePosition parses a FILE:LINE position.
+func parsePosition(position string) (string, int, error) {
+	i := strings.LastIndex(position, ":")
+	if i <= 0 {
+		return "", 0, fmt.Errorf("invalid position `%s': expected FILE:LINE", position)
+	}
+	line, err := strconv.Atoi(position[i+1:])
+	if err != nil || line < 1 {
+		return "", 0, fmt.Errorf("invalid position `%s': expected FILE:LINE", position)
+	}
+	return position[:i], line, nil
+}
+
+// explainLine writes why the line at the FILE:LINE position is removed or kept to w.
+func explainLine(w io.Writer, position string, configs *configLoader) error {
+	path, line, err := parsePosition(position)
+	if err != nil {
+		return err
+	}
+	s, err := configs.settingsFor(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read file `%s': %w", path, err)
+	}
+	pos := fmt.Sprintf("%s:%d", path, line)
+	if !s.includeGenerated && goremovelines.IsGenerated(string(src)) {
+		_, err := fmt.Fprintf(w, "%s: the line is kept, the file is generated (use --include-generated to clean it)\n", pos)
+		return err
+	}
+
+	e, err := goremovelines.Explain(string(src), line, goremovelines.Options{
+		Mode:     s.mode,
+		Filename: path,
+		Tolerant: *tolerantFlag,
+		Fragment: *fragmentFlag,
+	})
+	if err != nil {
+		var parseErr *goremovelines.ParseError
+		if errors.As(err, &parseErr) {
+			return cleanError(path, err)
+		}
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	switch {
+	case !e.Blank:
+		fmt.Fprintf(w, "%s: the line is not blank, only blank lines are removed\n", pos)
+	case e.Skipped:
+		fmt.Fprintf(w, "%s: the line is kept, it is part of a declaration with syntax errors\n", pos)
+	case e.Removed:
+		fmt.Fprintf(w, "%s: the blank line is removed\n", pos)
+	case e.Mode == 0:
+		fmt.Fprintf(w, "%s: the blank line is kept, it is not at the start or the end of a body that is cleaned\n", pos)
+	default:
+		fmt.Fprintf(w, "%s: the blank line is kept\n", pos)
+	}
+
+	if e.Node != "" {
+		fmt.Fprintf(w, "  construct: %s at %s\n", e.Node, e.NodePos)
+	}
+	if len(e.Path) > 0 {
+		fmt.Fprintf(w, "  path:      %s\n", strings.Join(e.Path, " in "))
+	}
+	if e.Mode == 0 {
+		return nil
+	}
+
+	switch {
+	case e.Removed:
+		fmt.Fprintf(w, "  mode:      %s\n", e.Mode)
+		fmt.Fprintf(w, "  keep it:   --keep=%s, `keep: [%s]' in %s or `//goremovelines:ignore %s' in the line above line %d\n",
+			e.Mode, e.Mode, configFileName, e.Mode, e.NodePos.Line)
+	case e.Directive != "":
+		fmt.Fprintf(w, "  mode:      %s, it is disabled by the directive `%s' at %s\n", e.Mode, e.Directive, e.DirectivePos)
+	case len(s.modeSources) > 0:
+		fmt.Fprintf(w, "  mode:      %s, it is not enabled by the remove and keep settings of %s\n",
+			e.Mode, strings.Join(s.modeSources, ", "))
+	default:
+		fmt.Fprintf(w, "  mode:      %s, it is not enabled by --remove and --keep\n", e.Mode)
+	}
+	return nil
+}
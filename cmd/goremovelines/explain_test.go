@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplainLine(t *testing.T) {
+	remove := *removeLineFlag
+	*removeLineFlag = []string{"all"}
+	defer func() {
+		*removeLineFlag = remove
+	}()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.go")
+	require.NoError(t, os.WriteFile(path, []byte(`package a
+
+func a() {
+
+	switch {
+	case true:
+
+		b()
+	}
+	//goremovelines:ignore
+	if true {
+
+	}
+}
+`), 0o600))
+	config := filepath.Join(dir, configFileName)
+	require.NoError(t, os.WriteFile(config, []byte("keep: [case]\n"), 0o600))
+
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{"2", ":2: the blank line is kept, it is not at the start or the end of a body that is cleaned\n"},
+		{"3", ":3: the line is not blank, only blank lines are removed\n" +
+			"  path:      *ast.FieldList in *ast.FuncType in *ast.FuncDecl\n"},
+		{"4", ":4: the blank line is removed\n" +
+			"  construct: *ast.FuncDecl at " + path + ":3:1\n" +
+			"  path:      *ast.BlockStmt in *ast.FuncDecl\n" +
+			"  mode:      func\n" +
+			"  keep it:   --keep=func, `keep: [func]' in .goremovelines.yaml or `//goremovelines:ignore func' in the line above line 3\n"},
+		{"7", ":7: the blank line is kept\n" +
+			"  construct: *ast.CaseClause at " + path + ":6:2\n" +
+			"  path:      *ast.CaseClause in *ast.BlockStmt in *ast.SwitchStmt in *ast.BlockStmt in *ast.FuncDecl\n" +
+			"  mode:      case, it is not enabled by the remove and keep settings of " + config + "\n"},
+		{"12", ":12: the blank line is kept\n" +
+			"  construct: *ast.IfStmt at " + path + ":11:2\n" +
+			"  path:      *ast.BlockStmt in *ast.IfStmt in *ast.BlockStmt in *ast.FuncDecl\n" +
+			"  mode:      if, it is disabled by the directive `//goremovelines:ignore' at " + path + ":10:2\n"},
+	}
+
+	for i, test := range tests {
+		var buf bytes.Buffer
+		require.NoError(t, explainLine(&buf, path+":"+test.line, newConfigLoader()), "Test %d failed", i)
+		require.Equal(t, path+test.expected, buf.String(), "Test %d failed", i)
+	}
+
+	// without configs the flags set the mode
+	var buf bytes.Buffer
+	*keepFlag = []string{"case"}
+	defer func() {
+		*keepFlag = nil
+	}()
+	require.NoError(t, explainLine(&buf, path+":7", nil))
+	require.Contains(t, buf.String(), "  mode:      case, it is not enabled by --remove and --keep\n")
+
+	for _, position := range []string{path, path + ":x", path + ":0", ":1"} {
+		require.Error(t, explainLine(&buf, position, nil), position)
+	}
+	require.EqualError(t, explainLine(&buf, path+":99", nil), path+": Line 99 is out of range (1-14)")
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const (
+	journalDirName  = "last-run"
+	journalFileName = "journal.json"
+	stateDirPerm    = 0o700
+	stateFilePerm   = 0o600
+)
+
+// journalEntry describes one file that was rewritten during a run.
+type journalEntry struct {
+	// Path is the absolute path of the rewritten file.
+	Path string `json:"path"`
+	// Original is the name of the copy of the original content inside the journal directory.
+	Original string `json:"original"`
+	// Hash is the sha256 of the content that was written, it is used to detect later modifications.
+	Hash string `json:"hash"`
+}
+
+// journal records the files rewritten by a run so that `goremovelines undo` can restore them.
+// The journal of the last run that rewrote at least one file is kept in the state directory.
+type journal struct {
+	Time    time.Time      `json:"time"`
+	Entries []journalEntry `json:"entries"`
+
+	dir     string
+	started bool
+}
+
+// stateDir returns the directory goremovelines keeps its state in.
+// It can be overwritten with the GOREMOVELINES_STATE_DIR environment variable.
+func stateDir() (string, error) {
+	if dir := os.Getenv("GOREMOVELINES_STATE_DIR"); dir != "" {
+		return dir, nil
+	}
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "goremovelines"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("unable to determine state directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "state", "goremovelines"), nil
+}
+
+func newJournal() (*journal, error) {
+	dir, err := stateDir()
+	if err != nil {
+		return nil, err
+	}
+	return &journal{dir: filepath.Join(dir, journalDirName)}, nil
+}
+
+func loadJournal() (*journal, error) {
+	j, err := newJournal()
+	if err != nil {
+		return nil, err
+	}
+	buf, err := os.ReadFile(filepath.Join(j.dir, journalFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.New("there is no run to undo")
+		}
+		return nil, fmt.Errorf("unable to read journal: %w", err)
+	}
+	if err := json.Unmarshal(buf, j); err != nil {
+		return nil, fmt.Errorf("unable to parse journal: %w", err)
+	}
+	j.started = true
+	return j, nil
+}
+
+// record stores the original content of path before it gets replaced by written.
+// The journal of the previous run is discarded when the first file gets recorded,
+// the journal is written after every file so that an interrupted run can be undone.
+func (j *journal) record(path string, original, written []byte) error {
+	if !j.started {
+		if err := os.RemoveAll(j.dir); err != nil {
+			return fmt.Errorf("unable to remove old journal: %w", err)
+		}
+		if err := os.MkdirAll(j.dir, stateDirPerm); err != nil {
+			return fmt.Errorf("unable to create journal: %w", err)
+		}
+		j.Time = time.Now()
+		j.started = true
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("unable to resolve `%s': %w", path, err)
+	}
+
+	entry := journalEntry{
+		Path:     abs,
+		Original: strconv.Itoa(len(j.Entries)) + ".orig",
+		Hash:     hashContent(written),
+	}
+	if err := os.WriteFile(filepath.Join(j.dir, entry.Original), original, stateFilePerm); err != nil {
+		return fmt.Errorf("unable to store original of `%s': %w", path, err)
+	}
+	j.Entries = append(j.Entries, entry)
+	return j.write()
+}
+
+// save writes the journal to the state directory, a journal without entries is not saved.
+func (j *journal) save() error {
+	if !j.started {
+		return nil
+	}
+	if len(j.Entries) == 0 {
+		if err := os.RemoveAll(j.dir); err != nil {
+			return fmt.Errorf("unable to remove journal: %w", err)
+		}
+		return nil
+	}
+	return j.write()
+}
+
+// write replaces the journal file, it is written to a temporary file first so that it is never left half written.
+func (j *journal) write() error {
+	buf, err := json.MarshalIndent(j, "", "\t")
+	if err != nil {
+		return fmt.Errorf("unable to encode journal: %w", err)
+	}
+	path := filepath.Join(j.dir, journalFileName)
+	if err := os.WriteFile(path+".tmp", buf, stateFilePerm); err != nil {
+		return fmt.Errorf("unable to write journal: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("unable to write journal: %w", err)
+	}
+	return nil
+}
+
+// undo restores all files of the journal.
+// Files that were modified after the run are not restored and stay in the journal.
+func (j *journal) undo() error {
+	var remaining []journalEntry
+	var failed int
+	for _, entry := range j.Entries {
+		if err := j.restore(entry); err != nil {
+			warningf("%v", err)
+			remaining = append(remaining, entry)
+			failed++
+			continue
+		}
+		debugf("restored %s", entry.Path)
+	}
+	j.Entries = remaining
+	if err := j.save(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d file(s) could not be restored", failed)
+	}
+	return nil
+}
+
+func (j *journal) restore(entry journalEntry) error {
+	current, err := os.ReadFile(entry.Path)
+	if err != nil {
+		return fmt.Errorf("unable to read `%s': %w", entry.Path, err)
+	}
+	original, err := os.ReadFile(filepath.Join(j.dir, entry.Original))
+	if err != nil {
+		return fmt.Errorf("unable to read original of `%s': %w", entry.Path, err)
+	}
+	if bytes.Equal(current, original) {
+		// the run was interrupted before the file was written
+		return nil
+	}
+	if hashContent(current) != entry.Hash {
+		return fmt.Errorf("refusing to restore `%s': file was modified after the run", entry.Path)
+	}
+	if err := writeFile(entry.Path, original); err != nil {
+		return err
+	}
+	return nil
+}
+
+func hashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// writeSource writes the cleaned content to path, files that did not change are not touched.
+// The original is recorded in the journal and, if a backup suffix is set, copied to path+suffix.
+func writeSource(path string, original, content []byte, j *journal, backupSuffix string) error {
+	if bytes.Equal(original, content) {
+		return nil
+	}
+	if backupSuffix != "" {
+		if err := writeFile(path+backupSuffix, original); err != nil {
+			return err
+		}
+	}
+	if err := j.record(path, original, content); err != nil {
+		return err
+	}
+	return writeFile(path, content)
+}
+
+func writeFile(path string, content []byte) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("unable to create file `%s': %w", path, err)
+	}
+	if _, err = f.Write(content); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("unable to write file `%s': %w", path, err)
+	}
+	if err = f.Close(); err != nil {
+		return fmt.Errorf("unable to close file `%s': %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournal(t *testing.T) {
+	t.Setenv("GOREMOVELINES_STATE_DIR", t.TempDir())
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.go")
+	b := filepath.Join(dir, "b.go")
+	require.NoError(t, os.WriteFile(a, []byte("a original"), 0o600))
+	require.NoError(t, os.WriteFile(b, []byte("b original"), 0o600))
+
+	// nothing was written yet
+	_, err := loadJournal()
+	require.EqualError(t, err, "there is no run to undo")
+
+	j, err := newJournal()
+	require.NoError(t, err)
+	require.NoError(t, writeSource(a, []byte("a original"), []byte("a cleaned"), j, ".bak"))
+	require.NoError(t, writeSource(b, []byte("b original"), []byte("b cleaned"), j, ""))
+
+	// the journal is written without save, an interrupted run can be undone
+	loaded, err := loadJournal()
+	require.NoError(t, err)
+	require.Len(t, loaded.Entries, 2)
+	require.NoError(t, j.save())
+
+	backup, err := os.ReadFile(a + ".bak")
+	require.NoError(t, err)
+	require.Equal(t, "a original", string(backup))
+	require.NoFileExists(t, b+".bak")
+
+	// modified files are not restored and stay in the journal
+	require.NoError(t, os.WriteFile(b, []byte("b modified"), 0o600))
+	loaded, err = loadJournal()
+	require.NoError(t, err)
+	require.EqualError(t, loaded.undo(), "1 file(s) could not be restored")
+	requireContent(t, a, "a original")
+	requireContent(t, b, "b modified")
+
+	require.NoError(t, os.WriteFile(b, []byte("b cleaned"), 0o600))
+	loaded, err = loadJournal()
+	require.NoError(t, err)
+	require.Len(t, loaded.Entries, 1)
+	require.NoError(t, loaded.undo())
+	requireContent(t, b, "b original")
+	_, err = loadJournal()
+	require.EqualError(t, err, "there is no run to undo")
+}
+
+func TestJournalInterrupted(t *testing.T) {
+	t.Setenv("GOREMOVELINES_STATE_DIR", t.TempDir())
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.go")
+	require.NoError(t, os.WriteFile(a, []byte("a original"), 0o600))
+
+	// the previous run is kept until a file of the next run is recorded
+	j, err := newJournal()
+	require.NoError(t, err)
+	require.NoError(t, writeSource(a, []byte("a original"), []byte("a cleaned"), j, ""))
+	require.NoError(t, j.save())
+	j, err = newJournal()
+	require.NoError(t, err)
+	require.NoError(t, j.save())
+	loaded, err := loadJournal()
+	require.NoError(t, err)
+	require.Len(t, loaded.Entries, 1)
+
+	// the run is interrupted after the original was recorded, but before the file was written
+	require.NoError(t, os.WriteFile(a, []byte("a original"), 0o600))
+	j, err = newJournal()
+	require.NoError(t, err)
+	require.NoError(t, j.record(a, []byte("a original"), []byte("a cleaned")))
+	loaded, err = loadJournal()
+	require.NoError(t, err)
+	require.NoError(t, loaded.undo())
+	requireContent(t, a, "a original")
+}
+
+func requireContent(t *testing.T, path, expected string) {
+	t.Helper()
+	buf, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, expected, string(buf), path)
+}
@@ -7,9 +7,12 @@ import (
 	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/Eun/goremovelines"
+	"github.com/Eun/goremovelines/walker"
 	"github.com/alecthomas/kingpin/v2"
 )
 
@@ -19,14 +22,37 @@ var version string
 var commit string
 var date string
 
+// flags that were set by the user take precedence over config files.
+var (
+	removeLineFlagSet       bool
+	keepFlagSet             bool
+	skipFlagSet             bool
+	includeGeneratedFlagSet bool
+	includeFlagSet          bool
+	excludeFlagSet          bool
+	skipHiddenFlagSet       bool
+	formatFlagSet           bool
+	pipeFlagSet             bool
+	pipeTimeoutFlagSet      bool
+)
+
 var (
 	removeLineFlag = kingpin.CommandLine.Flag(
 		"remove",
 		"Remove blank lines for the context (specify it multiple times, e.g.: --remove=func --remove=struct)",
 	).
 		Short('r').
-		PlaceHolder("func|struct|if|switch|case|for|interface|block").
+		PlaceHolder("func|struct|if|switch|case|for|interface|block|all").
 		Default("func", "struct", "if", "switch", "case", "for", "interface", "block").
+		IsSetByUser(&removeLineFlagSet).
+		Strings()
+	keepFlag = kingpin.CommandLine.Flag(
+		"keep",
+		"Keep blank lines for the context, even if it is removed with --remove (e.g.: --remove=all --keep=case)",
+	).
+		Short('k').
+		PlaceHolder("func|struct|if|switch|case|for|interface|block|all").
+		IsSetByUser(&keepFlagSet).
 		Strings()
 	writeToSourceFlag = kingpin.CommandLine.Flag(
 		"toSource",
@@ -41,11 +67,104 @@ var (
 	).
 		Short('s').
 		PlaceHolder("DIR...").
+		IsSetByUser(&skipFlagSet).
+		Strings()
+	includeFlag = kingpin.CommandLine.Flag(
+		"include",
+		"Only clean files matching this glob pattern (relative to the root of '...', `**` matches any number of directories).",
+	).
+		PlaceHolder("GLOB...").
+		IsSetByUser(&includeFlagSet).
+		Strings()
+	excludeFlag = kingpin.CommandLine.Flag(
+		"exclude",
+		"Skip files and directories matching this glob pattern (relative to the root of '...', `**` matches any number of directories).",
+	).
+		PlaceHolder("GLOB...").
+		IsSetByUser(&excludeFlagSet).
 		Strings()
-	vendorFlag = kingpin.CommandLine.Flag(
+	skipHiddenFlag = kingpin.CommandLine.Flag(
+		"skip-hidden",
+		"Skip files and directories whose name starts with '_' or '.' when expanding '...'.",
+	).
+		Default("true").
+		IsSetByUser(&skipHiddenFlagSet).
+		Bool()
+	cacheFlag = kingpin.CommandLine.Flag(
+		"cache",
+		"Skip files that are known to be clean from previous runs.",
+	).
+		Default("true").
+		Bool()
+	clearCacheFlag = kingpin.CommandLine.Flag(
+		"clear-cache",
+		"Remove all entries from the cache before cleaning.",
+	).
+		Bool()
+	formatFlag = kingpin.CommandLine.Flag(
+		"format",
+		"Output format if the result is written to stdout (raw, txtar or json), defaults to txtar for multiple files and raw otherwise.",
+	).
+		PlaceHolder("raw|txtar|json").
+		IsSetByUser(&formatFlagSet).
+		Enum(outputFormats...)
+	pipeFlag = kingpin.CommandLine.Flag(
+		"pipe",
+		"Run this command (reading stdin, writing stdout) after the cleaning (specify it multiple times for more commands), "+
+			"use "+cleanStep+" to run the cleaning at another position (e.g.: --pipe=gofumpt --pipe="+cleanStep+" --pipe=goimports).",
+	).
+		PlaceHolder("CMD").
+		IsSetByUser(&pipeFlagSet).
+		Strings()
+	pipeTimeoutFlag = kingpin.CommandLine.Flag(
+		"pipe-timeout",
+		"The time a single --pipe command may take.",
+	).
+		Default(defaultPipeTimeout.String()).
+		IsSetByUser(&pipeTimeoutFlagSet).
+		Duration()
+	keepGoingFlag = kingpin.CommandLine.Flag(
+		"keep-going",
+		"Continue with the remaining files if a file could not be cleaned and report all errors at the end.",
+	).
+		Default("true").
+		Bool()
+	stdinFilenameFlag = kingpin.CommandLine.Flag(
+		"stdin-filename",
+		"The path of the source that is read from stdin, its config is used and -w writes the result to it.",
+	).
+		PlaceHolder("PATH").
+		String()
+	filesFromFlag = kingpin.CommandLine.Flag(
+		"files-from",
+		"Read the files to clean from this file (one per line, use - for stdin).",
+	).
+		PlaceHolder("FILE").
+		String()
+	nullFlag = kingpin.CommandLine.Flag(
+		"null",
+		"The entries of --files-from are separated by NUL characters instead of newlines.",
+	).
+		Short('0').
+		Bool()
+	ignoreFilesFlag = kingpin.CommandLine.Flag(
+		"ignore-files",
+		"Skip files and directories that are ignored by .gitignore, .git/info/exclude and "+walker.IgnoreFileName+" files when expanding '...'.",
+	).
+		Default("true").
+		Bool()
+	tagsFlag = kingpin.CommandLine.Flag(
+		"tags",
+		"Only clean files that satisfy the build constraints with this comma separated list of build tags.",
+	).
+		PlaceHolder("TAG,...").
+		String()
+	// vendor directories are always skipped, the flag is kept for compatibility.
+	_ = kingpin.CommandLine.Flag(
 		"vendor",
-		"Enable vendoring support (skips 'vendor' directories and sets GO15VENDOREXPERIMENT=1).",
+		"Enable vendoring support (skips 'vendor' directories).",
 	).
+		Hidden().
 		Bool()
 	debugFlag = kingpin.CommandLine.Flag(
 		"debug",
@@ -53,120 +172,423 @@ var (
 	).
 		Short('d').
 		Bool()
+	includeGeneratedFlag = kingpin.CommandLine.Flag(
+		"include-generated",
+		"Also clean files that are marked with a `// Code generated ... DO NOT EDIT.` comment.",
+	).
+		IsSetByUser(&includeGeneratedFlagSet).
+		Bool()
+	tolerantFlag = kingpin.CommandLine.Flag(
+		"tolerant",
+		"Clean files with syntax errors, declarations that contain syntax errors are left untouched.",
+	).
+		Bool()
+	fragmentFlag = kingpin.CommandLine.Flag(
+		"fragment",
+		"Also clean sources without a package clause (lists of declarations or statements) like gofmt does.",
+	).
+		Bool()
+	gofmtFlag = kingpin.CommandLine.Flag(
+		"gofmt",
+		"Format the result with gofmt, fails if the result is not stable under a second pass.",
+	).
+		Bool()
+	verifyFlag = kingpin.CommandLine.Flag(
+		"verify",
+		"Check that the cleaned source has the same syntax tree and comments as the original, files that differ are not changed.",
+	).
+		Bool()
+	configFlag = kingpin.CommandLine.Flag(
+		"config",
+		"Use the settings of "+configFileName+" files in the directories of the cleaned files and their parents.",
+	).
+		Default("true").
+		Bool()
+	statsFlag = kingpin.CommandLine.Flag(
+		"stats",
+		"Print statistics about the removed lines per mode, construct, directory and file in the format (defaults to "+statsText+
+			" if no format is given), the cleaned sources are only written with -w.",
+	).
+		PlaceHolder("text|json").
+		Enum(statsFormats...)
+	backupFlag = kingpin.CommandLine.Flag(
+		"backup",
+		"Keep a copy of every rewritten file with the given suffix (defaults to "+defaultBackupSuffix+" if no suffix is given).",
+	).
+		PlaceHolder("SUFFIX").
+		String()
+
+	cleanCommand = kingpin.CommandLine.Command(
+		"clean",
+		"Clean the given paths.",
+	).
+		Default()
+	pathsArg = cleanCommand.Arg(
+		"path",
+		"Files, directories or package patterns to format. <path>/... will recurse.",
+	).
+		Strings()
+	watchCommand = kingpin.CommandLine.Command(
+		"watch",
+		"Clean the files of the given paths whenever they change, until interrupted. "+
+			"Without -w the paths of files that are not clean are printed.",
+	)
+	watchPathsArg = watchCommand.Arg(
+		"path",
+		"Files, directories or package patterns to watch. <path>/... will recurse and picks up new files.",
+	).
+		Strings()
+	watchIntervalFlag = watchCommand.Flag(
+		"interval",
+		"How often the files are checked for changes.",
+	).
+		Default("500ms").
+		Duration()
+	watchDebounceFlag = watchCommand.Flag(
+		"debounce",
+		"Clean a file only after it did not change for this long.",
+	).
+		Default("200ms").
+		Duration()
+	explainCommand = kingpin.CommandLine.Command(
+		"explain",
+		"Explain why a line is removed or kept: the construct it belongs to, the responsible mode and how to keep it.",
+	)
+	explainPositionArg = explainCommand.Arg(
+		"position",
+		"The line to explain (e.g. main.go:12).",
+	).
+		Required().
+		String()
+	serveCommand = kingpin.CommandLine.Command(
+		"serve",
+		"Serve a JSON/HTTP endpoint (POST /clean) that cleans sources, until interrupted.",
+	)
+	serveAddrFlag = serveCommand.Flag(
+		"addr",
+		"The address to listen on.",
+	).
+		Default("localhost:8080").
+		String()
+	serveMaxSizeFlag = serveCommand.Flag(
+		"max-size",
+		"The maximum size of a request.",
+	).
+		Default("1MiB").
+		Bytes()
+	serveTimeoutFlag = serveCommand.Flag(
+		"timeout",
+		"The time a request may take, the cleaning of requests that time out is stopped.",
+	).
+		Default("10s").
+		Duration()
+	serveMaxConcurrentFlag = serveCommand.Flag(
+		"max-concurrent",
+		"The number of sources that are cleaned at the same time, other requests wait (0 for the number of CPUs).",
+	).
+		Default("0").
+		Int()
+	undoCommand = kingpin.CommandLine.Command(
+		"undo",
+		"Restore all files that were rewritten by the last run.",
+	)
 )
 
-func printMode(mode goremovelines.Mode) {
-	if debugFlag == nil || !*debugFlag {
-		return
-	}
-	debugf("Mode is %d", mode)
-	if mode&goremovelines.FuncMode == goremovelines.FuncMode {
-		debugf("> Cleaning for Funcs")
+const defaultBackupSuffix = ".orig"
+
+// parseMode parses the names of the modes to remove and the modes to keep.
+func parseMode(remove, keep []string) (goremovelines.Mode, error) {
+	mode, err := goremovelines.ParseMode(strings.Join(remove, ","))
+	if err != nil {
+		return 0, err
 	}
-	if mode&goremovelines.StructMode == goremovelines.StructMode {
-		debugf("> Cleaning for Structs")
+	keepMode, err := goremovelines.ParseMode(strings.Join(keep, ","))
+	if err != nil {
+		return 0, err
 	}
-	if mode&goremovelines.IfMode == goremovelines.IfMode {
-		debugf("> Cleaning for Ifs")
+	return mode &^ keepMode, nil
+}
+
+// run holds the state that is shared by all files of a run.
+type run struct {
+	configs *configLoader
+	journal *journal
+	cache   *cleanCache
+	stats   *stats
+	format  string
+}
+
+func newRun(configs *configLoader) (*run, error) {
+	r := &run{configs: configs}
+	if *cacheFlag {
+		var err error
+		r.cache, err = newCleanCache()
+		if err != nil {
+			return nil, err
+		}
 	}
-	if mode&goremovelines.SwitchMode == goremovelines.SwitchMode {
-		debugf("> Cleaning for Switches")
+	return r, nil
+}
+
+// clean cleans src of the file name with the settings, the result of sources that are known to be clean
+// is taken from the cache.
+func (r *run) clean(name string, src []byte, s settings) ([]byte, error) {
+	opts := goremovelines.Options{
+		Mode:     s.mode,
+		Filename: name,
+		Tolerant: *tolerantFlag,
+		Fragment: *fragmentFlag,
+		Gofmt:    *gofmtFlag,
+		Verify:   *verifyFlag,
 	}
-	if mode&goremovelines.CaseMode == goremovelines.CaseMode {
-		debugf("> Cleaning for Cases")
+	start := time.Now()
+	key := r.cache.key(src, opts, s.pipe.steps...)
+	if r.cache.isClean(key) {
+		r.stats.add(name, nil, time.Since(start))
+		return src, nil
 	}
-	if mode&goremovelines.ForMode == goremovelines.ForMode {
-		debugf("> Cleaning for For Loops")
+
+	goremovelines.Debug = *debugFlag
+	skipped := false
+	var removed []goremovelines.Removal
+	out, err := s.pipe.run(name, src, func(src []byte) ([]byte, error) {
+		result, err := goremovelines.Clean(string(src), opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range result.Skipped {
+			warningf("Skipped declaration at %s: %v", d.Pos, d.Errors)
+		}
+		skipped = skipped || len(result.Skipped) > 0
+		removed = append(removed, result.Removed...)
+		return []byte(result.Source), nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	if mode&goremovelines.InterfaceMode == goremovelines.InterfaceMode {
-		debugf("> Cleaning for Interfaces")
+	r.stats.add(name, removed, time.Since(start))
+	if bytes.Equal(src, out) && !skipped {
+		if err := r.cache.markClean(key); err != nil {
+			debugf("unable to write cache: %v", err)
+		}
 	}
+	return out, nil
 }
 
-func parseMode() (mode goremovelines.Mode) {
-	for _, flag := range *removeLineFlag {
-		switch strings.ToLower(flag) {
-		case "func":
-			mode |= goremovelines.FuncMode
-		case "struct":
-			mode |= goremovelines.StructMode
-		case "if":
-			mode |= goremovelines.IfMode
-		case "switch":
-			mode |= goremovelines.SwitchMode
-		case "case":
-			mode |= goremovelines.CaseMode
-		case "for":
-			mode |= goremovelines.ForMode
-		case "interface":
-			mode |= goremovelines.InterfaceMode
-		case "block":
-			mode |= goremovelines.BlockMode
-		}
-	}
-
-	printMode(mode)
-
-	return mode
-}
-
-func cleanPaths(paths []string, mode goremovelines.Mode) error {
-	for i := 0; i < len(paths); i++ {
-		out := &bytes.Buffer{}
-		goremovelines.Debug = *debugFlag
-		if err := goremovelines.CleanFilePath(paths[i], out, mode); err != nil {
+func cleanPaths(paths []string, configs *configLoader) (err error) {
+	r, err := newRun(configs)
+	if err != nil {
+		return err
+	}
+	if *statsFlag != "" {
+		r.stats = newStats()
+	}
+	if writeToSourceFlag != nil && *writeToSourceFlag {
+		r.journal, err = newJournal()
+		if err != nil {
 			return err
 		}
-		if writeToSourceFlag != nil && *writeToSourceFlag {
-			f, err := os.Create(paths[i])
-			if err == nil {
-				if _, err = f.Write(out.Bytes()); err != nil {
-					return fmt.Errorf("unable to write file `%s': %w", paths[i], err)
-				}
-				if err = f.Close(); err != nil {
-					return fmt.Errorf("unable to close file `%s': %w", paths[i], err)
-				}
-			} else {
-				return fmt.Errorf("unable to create file `%s': %w", paths[i], err)
+		defer func() {
+			if saveErr := r.journal.save(); saveErr != nil && err == nil {
+				err = saveErr
 			}
-		} else {
-			if _, err := io.Copy(os.Stdout, out); err != nil {
-				return fmt.Errorf("unable to write to stdout (`%s'): %w", paths[i], err)
+		}()
+	}
+
+	s, err := configs.settingsFor(".")
+	if err != nil {
+		return err
+	}
+	r.format = s.format
+	if r.format == "" {
+		r.format = formatRaw
+		if len(paths) > 1 {
+			r.format = formatTxtar
+		}
+	}
+
+	var failed []error
+	for _, path := range paths {
+		if err := r.cleanPath(path); err != nil {
+			if !*keepGoingFlag {
+				return err
 			}
+			failed = append(failed, err)
 		}
 	}
+	if err := r.stats.write(os.Stdout, *statsFlag); err != nil {
+		return fmt.Errorf("unable to write statistics: %w", err)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d file(s) could not be cleaned:\n%w", len(failed), len(paths), errors.Join(failed...))
+	}
 	return nil
 }
 
-func cleanPathsFromStdin(mode goremovelines.Mode) error {
-	in := &bytes.Buffer{}
-	_, err := io.Copy(in, os.Stdin)
+// cleanPath cleans a single file, the returned errors contain the path.
+func (r *run) cleanPath(path string) error {
+	s, err := r.configs.settingsFor(filepath.Dir(path))
 	if err != nil {
-		return fmt.Errorf("unable to copy stdin: %w", err)
+		return err
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read file `%s': %w", path, err)
+	}
+	if !s.includeGenerated && goremovelines.IsGenerated(string(src)) {
+		debugf("skipping generated file %s", path)
+		return nil
 	}
 
-	out := &bytes.Buffer{}
-	goremovelines.Debug = *debugFlag
-	if err := goremovelines.CleanFile(in.String(), out, mode); err != nil {
+	out, err := r.clean(path, src, s)
+	if err != nil {
+		return cleanError(path, err)
+	}
+	if writeToSourceFlag != nil && *writeToSourceFlag {
+		return writeSource(path, src, out, r.journal, *backupFlag)
+	}
+	if r.stats != nil {
+		// only the statistics are printed
+		return nil
+	}
+	if err := writeOutput(os.Stdout, r.format, path, out); err != nil {
+		return fmt.Errorf("unable to write to stdout (`%s'): %w", path, err)
+	}
+	return nil
+}
+
+// stdinName is the name of sources from stdin without --stdin-filename in error messages and output headers.
+const stdinName = "<standard input>"
+
+// cleanError adds the path to err, parse errors are returned as they are since they already contain the position.
+func cleanError(path string, err error) error {
+	var parseErr *goremovelines.ParseError
+	if errors.As(err, &parseErr) {
+		parseErr.Filename = path
+		return parseErr
+	}
+	return fmt.Errorf("unable to clean `%s': %w", path, err)
+}
+
+// cleanPathsFromStdin cleans the source from stdin.
+// If --stdin-filename is set, the settings of that file are used and the result can be written to it.
+func cleanPathsFromStdin(configs *configLoader) (err error) {
+	name := *stdinFilenameFlag
+	if name == "" {
+		configs = nil
+	}
+	s, err := configs.settingsFor(filepath.Dir(name))
+	if err != nil {
 		return err
 	}
+	r, err := newRun(configs)
+	if err != nil {
+		return err
+	}
+	if *statsFlag != "" {
+		r.stats = newStats()
+	}
+
+	in, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return fmt.Errorf("unable to copy stdin: %w", err)
+	}
+
+	out := in
+	if name != "" && !s.includeGenerated && goremovelines.IsGenerated(string(in)) {
+		debugf("skipping generated file %s", name)
+	} else {
+		cleanName := name
+		if cleanName == "" {
+			cleanName = stdinName
+		}
+		out, err = r.clean(cleanName, in, s)
+		if err != nil {
+			return cleanError(cleanName, err)
+		}
+	}
+
 	if writeToSourceFlag != nil && *writeToSourceFlag {
-		return errors.New("could not write to source if reading from stdin")
+		if name == "" {
+			return errors.New("could not write to source if reading from stdin without --stdin-filename")
+		}
+		original, err := os.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("unable to read file `%s': %w", name, err)
+		}
+		j, err := newJournal()
+		if err != nil {
+			return err
+		}
+		if err := writeSource(name, original, out, j, *backupFlag); err != nil {
+			return err
+		}
+		if err := j.save(); err != nil {
+			return err
+		}
+		return r.stats.write(os.Stdout, *statsFlag)
+	}
+	if r.stats != nil {
+		return r.stats.write(os.Stdout, *statsFlag)
 	}
-	if _, err := io.Copy(os.Stdout, out); err != nil {
+	if name == "" {
+		name = stdinName
+	}
+	if err := writeOutput(os.Stdout, s.format, name, out); err != nil {
 		return fmt.Errorf("unable to copy to stdout: %w", err)
 	}
 	return nil
 }
 
+// optionalValues are the default values of flags that can be passed without a value.
+var optionalValues = map[string]string{
+	"--backup": defaultBackupSuffix,
+	"--stats":  statsText,
+}
+
+// expandOptionalFlags appends the default value to flags of optionalValues that were passed without a value.
+func expandOptionalFlags(args []string) []string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if value, ok := optionalValues[arg]; ok {
+			args[i] = arg + "=" + value
+		}
+	}
+	return args
+}
+
+func undo() error {
+	j, err := loadJournal()
+	if err != nil {
+		return err
+	}
+	return j.undo()
+}
+
 func main() {
-	pathsArg := kingpin.Arg("path", "Directories to format. Defaults to \".\". <path>/... will recurse.").Strings()
 	kingpin.CommandLine.HelpFlag.Short('h')
 	kingpin.CommandLine.Version(fmt.Sprintf("goremovelines %s %s %s", version, commit, date))
 	kingpin.CommandLine.VersionFlag.Short('v')
 	kingpin.CommandLine.Help = "Remove leading / trailing blank lines in Go functions, structs, if, switches, blocks."
 
-	kingpin.Parse()
+	command := kingpin.MustParse(kingpin.CommandLine.Parse(expandOptionalFlags(os.Args[1:])))
+
+	if command == undoCommand.FullCommand() {
+		if err := undo(); err != nil {
+			warningf("Unable to undo: %v", err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *clearCacheFlag {
+		if err := clearCache(); err != nil {
+			warningf("Unable to clear cache: %v", err.Error())
+			os.Exit(1)
+		}
+	}
 
 	if removeLineFlag == nil {
 		log.Panic("parameter remove is nil")
@@ -175,30 +597,73 @@ func main() {
 		return
 	}
 
-	mode := parseMode()
+	mode, err := parseMode(*removeLineFlag, *keepFlag)
+	if err != nil {
+		warningf("Invalid mode: %v", err.Error())
+		os.Exit(1)
+	}
+	debugf("Mode is %d (%s)", mode, mode)
+
+	if skipFlag == nil {
+		skipFlag = &[]string{}
+	}
+
+	var configs *configLoader
+	if *configFlag {
+		configs = newConfigLoader()
+	}
+
+	if command == serveCommand.FullCommand() {
+		if err := serveHTTP(*serveAddrFlag, configs); err != nil {
+			warningf("Unable to serve: %v", err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	if command == explainCommand.FullCommand() {
+		if err := explainLine(os.Stdout, *explainPositionArg, configs); err != nil {
+			warningf("Unable to explain: %v", err.Error())
+			os.Exit(1)
+		}
+		return
+	}
+
+	if command == watchCommand.FullCommand() {
+		if err := watchPaths(*watchPathsArg, configs); err != nil {
+			warningf("Unable to watch: %v", err.Error())
+			os.Exit(1)
+		}
+		return
+	}
 
-	if pathsArg == nil || len(*pathsArg) == 0 {
-		if err := cleanPathsFromStdin(mode); err != nil {
+	if (pathsArg == nil || len(*pathsArg) == 0) && *filesFromFlag == "" {
+		if err := cleanPathsFromStdin(configs); err != nil {
 			warningf("Unable to clean: %v", err.Error())
 			os.Exit(1)
 		}
 		return
 	}
 
-	if skipFlag == nil {
-		skipFlag = &[]string{}
+	var paths []string
+	if len(*pathsArg) > 0 {
+		paths, err = resolvePaths(*pathsArg, configs)
+		if err != nil {
+			warningf("Unable to resolve paths: %v", err.Error())
+			os.Exit(1)
+		}
 	}
 
-	if os.Getenv("GO15VENDOREXPERIMENT") == "1" || (vendorFlag != nil && *vendorFlag) {
-		if err := os.Setenv("GO15VENDOREXPERIMENT", "1"); err != nil {
-			warningf("setenv GO15VENDOREXPERIMENT: %s", err)
+	if *filesFromFlag != "" {
+		listed, err := readFileList(*filesFromFlag, *nullFlag)
+		if err != nil {
+			warningf("Unable to read file list: %v", err.Error())
+			os.Exit(1)
 		}
-		*skipFlag = append(*skipFlag, "vendor")
-		trueValue := true
-		vendorFlag = &trueValue
+		paths = append(paths, listed...)
 	}
 
-	if err := cleanPaths(resolvePaths(*pathsArg, *skipFlag), mode); err != nil {
+	if err := cleanPaths(paths, configs); err != nil {
 		warningf("Unable to clean: %v", err.Error())
 		os.Exit(1)
 	}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// mainEnv makes the test binary run main with the arguments after `--', see runMain.
+const mainEnv = "GOREMOVELINES_TEST_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(mainEnv) != "" {
+		for i, arg := range os.Args {
+			if arg == "--" {
+				os.Args = append([]string{"goremovelines"}, os.Args[i+1:]...)
+				break
+			}
+		}
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runMain runs goremovelines with the arguments in a new process and returns its stdout, stderr and exit code.
+func runMain(t *testing.T, args ...string) (stdout, stderr string, code int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], append([]string{"-test.run=^$", "--"}, args...)...) //nolint:gosec // runs the test binary itself
+	cmd.Env = append(os.Environ(),
+		mainEnv+"=1",
+		"GOREMOVELINES_CACHE_DIR="+t.TempDir(),
+		"GOREMOVELINES_STATE_DIR="+t.TempDir(),
+	)
+	var out, errOut bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &errOut
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return out.String(), errOut.String(), exitErr.ExitCode()
+	}
+	require.NoError(t, err)
+	return out.String(), errOut.String(), 0
+}
+
+func TestCleanPathsKeepGoing(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.go")
+	b := filepath.Join(dir, "b.go")
+	c := filepath.Join(dir, "c.go")
+	d := filepath.Join(dir, "d.go")
+	require.NoError(t, os.WriteFile(a, []byte("package a\nfunc a() {\n\n}\n"), 0o600))
+	require.NoError(t, os.WriteFile(b, []byte("package a\nfunc b( {\n\n}\n"), 0o600))
+	require.NoError(t, os.WriteFile(c, []byte("package a\nfunc c() {\n\n}\n"), 0o600))
+	require.NoError(t, os.WriteFile(d, []byte("package a\nfunc d() {\n\tif {\n}\n"), 0o600))
+
+	// the files after a failure are cleaned, the summary lists every failed file and the exit status is 1
+	stdout, stderr, code := runMain(t, "-w", dir+"/...")
+	require.Equal(t, 1, code)
+	require.Empty(t, stdout)
+	require.Equal(t, "WARNING: Unable to clean: 2 of 4 file(s) could not be cleaned:\n"+
+		b+":2:9: expected ')', found '{' (and 1 more errors)\n"+
+		d+":3:5: missing condition in if statement (and 1 more errors)\n", stderr)
+	requireContent(t, a, "package a\nfunc a() {\n}\n")
+	requireContent(t, b, "package a\nfunc b( {\n\n}\n")
+	requireContent(t, c, "package a\nfunc c() {\n}\n")
+
+	// without --keep-going the first failure stops the run
+	require.NoError(t, os.WriteFile(c, []byte("package a\nfunc c() {\n\n}\n"), 0o600))
+	_, stderr, code = runMain(t, "-w", "--no-keep-going", dir+"/...")
+	require.Equal(t, 1, code)
+	require.Equal(t, "WARNING: Unable to clean: "+b+":2:9: expected ')', found '{' (and 1 more errors)\n", stderr)
+	requireContent(t, c, "package a\nfunc c() {\n\n}\n")
+}
+
+func TestCleanPathsFromStdin(t *testing.T) {
+	setFlag(t, removeLineFlag, []string{"all"})
+	src := "package a\nfunc a() {\n\n}\n"
+	clean := "package a\nfunc a() {\n}\n"
+
+	tests := []struct {
+		format   string
+		input    string
+		expected string
+		err      string
+	}{
+		{"", src, clean, ""},
+		{formatTxtar, src, "-- " + stdinName + " --\n" + clean, ""},
+		{formatJSON, src, `{"path":"\u003cstandard input\u003e","content":"package a\nfunc a() {\n}\n"}` + "\n", ""},
+		{"", "package a\nfunc a( {\n}\n", "", stdinName + ":2:9: expected ')', found '{' (and 1 more errors)"},
+	}
+
+	for i, test := range tests {
+		setFlag(t, formatFlag, test.format)
+		var err error
+		out := captureStdout(t, func() {
+			withStdin(t, test.input, func() {
+				err = cleanPathsFromStdin(newConfigLoader())
+			})
+		})
+		if test.err != "" {
+			require.EqualError(t, err, test.err, "Test %d failed", i)
+			continue
+		}
+		require.NoError(t, err, "Test %d failed", i)
+		require.Equal(t, test.expected, out, "Test %d failed", i)
+	}
+
+	// -w needs a file to write to
+	setFlag(t, writeToSourceFlag, true)
+	withStdin(t, src, func() {
+		require.EqualError(t, cleanPathsFromStdin(newConfigLoader()),
+			"could not write to source if reading from stdin without --stdin-filename")
+	})
+}
+
+func TestCleanPathsFromStdinFilename(t *testing.T) {
+	t.Setenv("GOREMOVELINES_STATE_DIR", t.TempDir())
+	setFlag(t, removeLineFlag, []string{"all"})
+	src := "package a\nfunc a() {\n\n\tif true {\n\n\t}\n}\n"
+
+	// the config of the directory of --stdin-filename is used
+	dir := t.TempDir()
+	writeConfig(t, dir, configFileName, "keep: [if]\n")
+	path := filepath.Join(dir, "a.go")
+	require.NoError(t, os.WriteFile(path, []byte("package a\n"), 0o600))
+	setFlag(t, stdinFilenameFlag, path)
+	var err error
+	out := captureStdout(t, func() {
+		withStdin(t, src, func() {
+			err = cleanPathsFromStdin(newConfigLoader())
+		})
+	})
+	require.NoError(t, err)
+	require.Equal(t, "package a\nfunc a() {\n\tif true {\n\n\t}\n}\n", out)
+
+	// without configs only the flags are used
+	out = captureStdout(t, func() {
+		withStdin(t, src, func() {
+			err = cleanPathsFromStdin(nil)
+		})
+	})
+	require.NoError(t, err)
+	require.Equal(t, "package a\nfunc a() {\n\tif true {\n\t}\n}\n", out)
+
+	// -w writes the result to --stdin-filename and records it in the journal
+	setFlag(t, writeToSourceFlag, true)
+	out = captureStdout(t, func() {
+		withStdin(t, src, func() {
+			err = cleanPathsFromStdin(newConfigLoader())
+		})
+	})
+	require.NoError(t, err)
+	require.Empty(t, out)
+	requireContent(t, path, "package a\nfunc a() {\n\tif true {\n\n\t}\n}\n")
+	j, err := loadJournal()
+	require.NoError(t, err)
+	require.NoError(t, j.undo())
+	requireContent(t, path, "package a\n")
+}
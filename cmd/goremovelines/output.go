@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// output formats for cleaned files that are written to stdout.
+const (
+	// formatRaw writes the cleaned files without any separator.
+	formatRaw = "raw"
+	// formatTxtar writes the cleaned files as a txtar archive (a `-- path --` header per file).
+	formatTxtar = "txtar"
+	// formatJSON writes a stream of {"path": ..., "content": ...} objects, one per line.
+	formatJSON = "json"
+)
+
+var outputFormats = []string{formatRaw, formatTxtar, formatJSON}
+
+func isOutputFormat(format string) bool {
+	for _, f := range outputFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+type outputFile struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// writeOutput writes the cleaned content of path in the format to w.
+func writeOutput(w io.Writer, format, path string, content []byte) error {
+	switch format {
+	case formatTxtar:
+		if _, err := fmt.Fprintf(w, "-- %s --\n", path); err != nil {
+			return err
+		}
+		if _, err := w.Write(content); err != nil {
+			return err
+		}
+		if len(content) > 0 && content[len(content)-1] != '\n' {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		return nil
+	case formatJSON:
+		return json.NewEncoder(w).Encode(outputFile{Path: path, Content: string(content)})
+	default:
+		_, err := w.Write(content)
+		return err
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteOutput(t *testing.T) {
+	tests := []struct {
+		format   string
+		content  string
+		expected string
+	}{
+		{formatRaw, "package main\n", "package main\n"},
+		{formatTxtar, "package main\n", "-- main.go --\npackage main\n"},
+		{formatTxtar, "package main", "-- main.go --\npackage main\n"},
+		{formatJSON, "package main\n", "{\"path\":\"main.go\",\"content\":\"package main\\n\"}\n"},
+	}
+
+	for i, test := range tests {
+		var buf bytes.Buffer
+		require.NoError(t, writeOutput(&buf, test.format, "main.go", []byte(test.content)), "Test %d failed", i)
+		require.Equal(t, test.expected, buf.String(), "Test %d failed", i)
+	}
+}
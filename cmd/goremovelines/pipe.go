@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// cleanStep is the pipeline step that runs the built-in cleaning.
+const cleanStep = "@clean"
+
+// defaultPipeTimeout is the time a single pipeline step may take.
+const defaultPipeTimeout = 30 * time.Second
+
+// pipeline runs external formatters before and after the built-in cleaning.
+// Every step reads the source from stdin and writes the result to stdout.
+type pipeline struct {
+	steps   []string
+	timeout time.Duration
+}
+
+// run runs all steps of the pipeline for the source of the file name, clean is the built-in cleaning.
+// If the steps do not contain cleanStep, the cleaning runs first.
+// Errors of a command contain its number in the list of steps.
+func (p pipeline) run(name string, src []byte, clean func([]byte) ([]byte, error)) ([]byte, error) {
+	var err error
+	if !containsString(p.steps, cleanStep) {
+		src, err = clean(src)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i, step := range p.steps {
+		if step == cleanStep {
+			src, err = clean(src)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		src, err = p.runCommand(name, step, src)
+		if err != nil {
+			return nil, fmt.Errorf("step %d (`%s') failed: %w", i+1, step, err)
+		}
+	}
+	return src, nil
+}
+
+// runCommand runs the command with src as stdin and returns its stdout.
+// The environment variable GOREMOVELINES_FILENAME contains the name of the file.
+func (p pipeline) runCommand(name, command string, src []byte) ([]byte, error) {
+	args, err := splitCommand(command)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, errors.New("empty command")
+	}
+
+	timeout := p.timeout
+	if timeout <= 0 {
+		timeout = defaultPipeTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...) //nolint:gosec // the commands are configured by the user
+	cmd.Stdin = bytes.NewReader(src)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = append(os.Environ(), "GOREMOVELINES_FILENAME="+name)
+	// do not wait for children of a killed command that keep stdout open
+	cmd.WaitDelay = time.Second
+	debugf("running %s for %s", command, name)
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("timed out after %s", timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	if stdout.Len() == 0 && len(src) > 0 {
+		return nil, errors.New("no output")
+	}
+	return stdout.Bytes(), nil
+}
+
+// stepFiles returns the resolved executable of the pipeline step and the arguments that are existing files
+// (e.g. the script of `sh fmt.sh'), they are part of the cache keys.
+func stepFiles(step string) ([]string, error) {
+	args, err := splitCommand(step)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, errors.New("empty command")
+	}
+	exe, err := exec.LookPath(args[0])
+	if err != nil {
+		return nil, err
+	}
+	files := []string{exe}
+	for _, arg := range args[1:] {
+		if fi, err := os.Stat(arg); err == nil && fi.Mode().IsRegular() {
+			files = append(files, arg)
+		}
+	}
+	return files, nil
+}
+
+// splitCommand splits a command line into its arguments,
+// arguments can be quoted with single or double quotes and characters can be escaped with a backslash.
+func splitCommand(command string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, r := range command {
+		switch {
+		case escaped:
+			arg.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			arg.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape in `%s'", command)
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		command  string
+		expected []string
+	}{
+		{"gofumpt", []string{"gofumpt"}},
+		{"  goimports  -local example.com ", []string{"goimports", "-local", "example.com"}},
+		{`sed 's/a b/c/'`, []string{"sed", "s/a b/c/"}},
+		{`sh -c "echo \"x\" y"`, []string{"sh", "-c", `echo "x" y`}},
+		{`a\ b ''`, []string{"a b", ""}},
+		{"", nil},
+	}
+
+	for i, test := range tests {
+		args, err := splitCommand(test.command)
+		require.NoError(t, err, "Test %d failed", i)
+		require.Equal(t, test.expected, args, "Test %d failed", i)
+	}
+
+	_, err := splitCommand(`sed 's/a`)
+	require.Error(t, err)
+}
+
+// writeScript writes an executable shell script and returns its path.
+func writeScript(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+content+"\n"), 0o700)) //nolint:gosec // test script
+	return path
+}
+
+func TestPipeline(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("stub scripts need a shell")
+	}
+
+	// clean appends a marker, so the order of the steps is visible
+	clean := func(src []byte) ([]byte, error) {
+		return append(src, "clean\n"...), nil
+	}
+	appendA := writeScript(t, "a", `cat; echo a`)
+	appendName := writeScript(t, "name", `cat; echo "$GOREMOVELINES_FILENAME"`)
+	fail := writeScript(t, "fail", `echo "something went wrong" >&2; exit 3`)
+	sleep := writeScript(t, "sleep", `sleep 5; cat`)
+	empty := writeScript(t, "empty", `cat > /dev/null`)
+
+	tests := []struct {
+		steps    []string
+		expected string
+		err      string
+	}{
+		{nil, "src\nclean\n", ""},
+		{[]string{appendA}, "src\nclean\na\n", ""},
+		{[]string{appendA, cleanStep, appendA}, "src\na\nclean\na\n", ""},
+		{[]string{appendName}, "src\nclean\nfile.go\n", ""},
+		{[]string{appendA, fail}, "", "step 2 (`" + fail + "') failed: exit status 3: something went wrong"},
+		{[]string{sleep}, "", "step 1 (`" + sleep + "') failed: timed out after 200ms"},
+		{[]string{empty}, "", "step 1 (`" + empty + "') failed: no output"},
+		{[]string{"'unterminated"}, "", "step 1 (`'unterminated') failed: unterminated quote or escape in `'unterminated'"},
+	}
+
+	for i, test := range tests {
+		p := pipeline{steps: test.steps, timeout: 200 * time.Millisecond}
+		out, err := p.run("file.go", []byte("src\n"), clean)
+		if test.err != "" {
+			require.EqualError(t, err, test.err, "Test %d failed", i)
+			continue
+		}
+		require.NoError(t, err, "Test %d failed", i)
+		require.Equal(t, test.expected, string(out), "Test %d failed", i)
+	}
+}
+
+func TestPipelineCleanError(t *testing.T) {
+	p := pipeline{steps: []string{"does-not-run"}}
+	_, err := p.run("file.go", []byte("src\n"), func([]byte) ([]byte, error) {
+		return nil, os.ErrInvalid
+	})
+	require.ErrorIs(t, err, os.ErrInvalid)
+	require.False(t, strings.Contains(err.Error(), "step"))
+}
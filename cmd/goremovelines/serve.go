@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Eun/goremovelines"
+)
+
+const (
+	// shutdownTimeout is the time running requests get to finish after the server was interrupted.
+	shutdownTimeout = 10 * time.Second
+	// readHeaderTimeout is the time a client gets to send the request headers.
+	readHeaderTimeout = 5 * time.Second
+	// idleTimeout is the time an idle keep-alive connection is kept open.
+	idleTimeout = time.Minute
+)
+
+// serveRequest is the body of a request to the /clean endpoint.
+type serveRequest struct {
+	Source string `json:"source"`
+	// Filename is only used for the positions of diagnostics.
+	Filename string `json:"filename"`
+	// Mode is a comma separated list of modes (like --remove), the mode of the server is used if it is not set.
+	Mode     *goremovelines.Mode `json:"mode"`
+	Tolerant bool                `json:"tolerant"`
+	Fragment bool                `json:"fragment"`
+	Gofmt    bool                `json:"gofmt"`
+	Verify   bool                `json:"verify"`
+}
+
+// serveEdit replaces the bytes from Start to End of the source with Text.
+type serveEdit struct {
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Text  string `json:"text"`
+	// Line, Mode and Node describe a removed blank line, they are empty for edits that replace the whole source.
+	Line int    `json:"line,omitempty"`
+	Mode string `json:"mode,omitempty"`
+	Node string `json:"node,omitempty"`
+}
+
+// serveDiagnostic is an error or a warning about the source.
+type serveDiagnostic struct {
+	Severity string `json:"severity"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Message  string `json:"message"`
+}
+
+// serveResponse is the body of a response of the /clean endpoint.
+type serveResponse struct {
+	Source      string            `json:"source,omitempty"`
+	Changed     bool              `json:"changed"`
+	Edits       []serveEdit       `json:"edits"`
+	Diagnostics []serveDiagnostic `json:"diagnostics"`
+	Error       string            `json:"error,omitempty"`
+}
+
+// server serves the /clean endpoint, cleaning never changes global state so requests run concurrently.
+type server struct {
+	// mode is used for requests without a mode.
+	mode    goremovelines.Mode
+	maxSize int64
+	timeout time.Duration
+	// slots limits the number of sources that are cleaned at the same time.
+	slots chan struct{}
+	// clean cleans a source until ctx is done, it is goremovelines.CleanContext.
+	clean func(ctx context.Context, src string, opts goremovelines.Options) (*goremovelines.Result, error)
+}
+
+// newServer returns a server that cleans at most maxConcurrent sources at the same time (the number of CPUs if it is 0).
+func newServer(mode goremovelines.Mode, maxSize int64, timeout time.Duration, maxConcurrent int) *server {
+	if maxConcurrent <= 0 {
+		maxConcurrent = runtime.NumCPU()
+	}
+	return &server{
+		mode:    mode,
+		maxSize: maxSize,
+		timeout: timeout,
+		slots:   make(chan struct{}, maxConcurrent),
+		clean:   goremovelines.CleanContext,
+	}
+}
+
+// handler returns the handler of all endpoints, requests that take longer than the timeout are answered with 503
+// and their cleaning is stopped.
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/clean", s.handleClean)
+	return http.TimeoutHandler(mux, s.timeout, `{"error":"timeout"}`)
+}
+
+func (s *server) handleClean(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, &serveResponse{Error: "method not allowed"})
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxSize)
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	var req serveRequest
+	if err := dec.Decode(&req); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeJSON(w, http.StatusRequestEntityTooLarge, &serveResponse{
+				Error: fmt.Sprintf("request is larger than %d bytes", maxErr.Limit),
+			})
+			return
+		}
+		writeJSON(w, http.StatusBadRequest, &serveResponse{Error: "invalid request: " + err.Error()})
+		return
+	}
+
+	opts := goremovelines.Options{
+		Mode:     s.mode,
+		Filename: req.Filename,
+		Tolerant: req.Tolerant,
+		Fragment: req.Fragment,
+		Gofmt:    req.Gofmt,
+		Verify:   req.Verify,
+	}
+	if req.Mode != nil {
+		opts.Mode = *req.Mode
+	}
+
+	// requests that wait for a slot are answered by the timeout handler if they time out
+	ctx := r.Context()
+	select {
+	case s.slots <- struct{}{}:
+		defer func() {
+			<-s.slots
+		}()
+	case <-ctx.Done():
+		return
+	}
+	result, err := s.clean(ctx, req.Source, opts)
+	if ctx.Err() != nil {
+		// the timeout handler already answered
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusUnprocessableEntity, &serveResponse{
+			Diagnostics: errorDiagnostics(err),
+			Error:       err.Error(),
+		})
+		return
+	}
+
+	resp := &serveResponse{
+		Source:  result.Source,
+		Changed: result.Source != req.Source,
+		Edits:   edits(req.Source, result),
+	}
+	for _, d := range result.Skipped {
+		for _, e := range d.Errors {
+			resp.Diagnostics = append(resp.Diagnostics, serveDiagnostic{
+				Severity: "warning",
+				Line:     e.Pos.Line,
+				Column:   e.Pos.Column,
+				Message:  "declaration was not cleaned: " + e.Msg,
+			})
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// edits returns the edits that turn src into the cleaned source: one edit per removed line,
+// or a single edit that replaces the whole source if the removals do not describe all changes (e.g. with gofmt).
+func edits(src string, result *goremovelines.Result) []serveEdit {
+	list := []serveEdit{}
+	if src == result.Source {
+		return list
+	}
+
+	// the offsets of the starts of all lines
+	starts := []int{0}
+	for i := 0; i < len(src); i++ {
+		if src[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	var applied strings.Builder
+	last := 0
+	for _, r := range result.Removed {
+		if r.Line < 1 || r.Line >= len(starts) {
+			break
+		}
+		e := serveEdit{Start: starts[r.Line-1], End: starts[r.Line], Line: r.Line, Mode: r.Mode.String(), Node: r.Node}
+		applied.WriteString(src[last:e.Start])
+		last = e.End
+		list = append(list, e)
+	}
+	applied.WriteString(src[last:])
+	if applied.String() == result.Source {
+		return list
+	}
+	return []serveEdit{{Start: 0, End: len(src), Text: result.Source}}
+}
+
+// errorDiagnostics converts the error of a failed cleaning into diagnostics.
+func errorDiagnostics(err error) []serveDiagnostic {
+	var parseErr *goremovelines.ParseError
+	if errors.As(err, &parseErr) {
+		list := make([]serveDiagnostic, 0, len(parseErr.Errors))
+		for _, e := range parseErr.Errors {
+			list = append(list, serveDiagnostic{Severity: "error", Line: e.Pos.Line, Column: e.Pos.Column, Message: e.Msg})
+		}
+		return list
+	}
+	var verifyErr *goremovelines.VerifyError
+	if errors.As(err, &verifyErr) {
+		return []serveDiagnostic{{
+			Severity: "error", Line: verifyErr.Pos.Line, Column: verifyErr.Pos.Column, Message: verifyErr.Error(),
+		}}
+	}
+	return []serveDiagnostic{{Severity: "error", Message: err.Error()}}
+}
+
+// writeJSON writes the response, edits and diagnostics are always lists.
+func writeJSON(w http.ResponseWriter, status int, resp *serveResponse) {
+	if resp.Edits == nil {
+		resp.Edits = []serveEdit{}
+	}
+	if resp.Diagnostics == nil {
+		resp.Diagnostics = []serveDiagnostic{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		debugf("unable to write response: %v", err)
+	}
+}
+
+// serve serves the endpoints on the listener until ctx is done, running requests get shutdownTimeout to finish.
+func (s *server) serve(ctx context.Context, ln net.Listener) error {
+	srv := &http.Server{
+		Handler:           s.handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       s.timeout,
+		// the timeout handler answers before the write timeout closes the connection
+		WriteTimeout: s.timeout + time.Second,
+		IdleTimeout:  idleTimeout,
+	}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	debugf("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("unable to shut down: %w", err)
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// serveHTTP serves the endpoints on the address until the process is interrupted.
+func serveHTTP(addr string, configs *configLoader) error {
+	st, err := configs.settingsFor(".")
+	if err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("unable to listen on `%s': %w", addr, err)
+	}
+	fmt.Fprintf(os.Stderr, "Listening on http://%s\n", ln.Addr())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return newServer(st.mode, int64(*serveMaxSizeFlag), *serveTimeoutFlag, *serveMaxConcurrentFlag).serve(ctx, ln)
+}
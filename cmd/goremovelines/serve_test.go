@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Eun/goremovelines"
+	"github.com/stretchr/testify/require"
+)
+
+// post sends body to the /clean endpoint of the handler and decodes the response.
+func post(t *testing.T, h http.Handler, body string) (int, serveResponse) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/clean", strings.NewReader(body)))
+	var resp serveResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp), rec.Body.String())
+	return rec.Code, resp
+}
+
+func TestServeClean(t *testing.T) {
+	h := newServer(goremovelines.AllMode, 1024, time.Minute, 0).handler()
+
+	tests := []struct {
+		body     string
+		status   int
+		expected serveResponse
+	}{
+		{
+			`{"source": "package a\nfunc a() {\n\n\tif true {\n\n\t}\n}\n"}`,
+			http.StatusOK,
+			serveResponse{
+				Source:  "package a\nfunc a() {\n\tif true {\n\t}\n}\n",
+				Changed: true,
+				Edits: []serveEdit{
+					{Start: 21, End: 22, Line: 3, Mode: "func", Node: "*ast.FuncDecl"},
+					{Start: 33, End: 34, Line: 5, Mode: "if", Node: "*ast.IfStmt"},
+				},
+				Diagnostics: []serveDiagnostic{},
+			},
+		},
+		{
+			`{"source": "package a\nfunc a() {\n\n\tif true {\n\n\t}\n}\n", "mode": "if"}`,
+			http.StatusOK,
+			serveResponse{
+				Source:      "package a\nfunc a() {\n\n\tif true {\n\t}\n}\n",
+				Changed:     true,
+				Edits:       []serveEdit{{Start: 33, End: 34, Line: 5, Mode: "if", Node: "*ast.IfStmt"}},
+				Diagnostics: []serveDiagnostic{},
+			},
+		},
+		{
+			// the formatting is not described by the removals, the whole source is replaced
+			`{"source": "package a\nfunc a() {\n\n\treturn  1\n}\n", "gofmt": true}`,
+			http.StatusOK,
+			serveResponse{
+				Source:      "package a\n\nfunc a() {\n\treturn 1\n}\n",
+				Changed:     true,
+				Edits:       []serveEdit{{Start: 0, End: 35, Text: "package a\n\nfunc a() {\n\treturn 1\n}\n"}},
+				Diagnostics: []serveDiagnostic{},
+			},
+		},
+		{
+			`{"source": "package a\nfunc a() {\n}\n"}`,
+			http.StatusOK,
+			serveResponse{
+				Source:      "package a\nfunc a() {\n}\n",
+				Edits:       []serveEdit{},
+				Diagnostics: []serveDiagnostic{},
+			},
+		},
+		{
+			`{"source": "package a\nfunc a() {\n\n\tb(\n}\n\nfunc c() {\n\n}\n", "tolerant": true}`,
+			http.StatusOK,
+			serveResponse{
+				Source:  "package a\nfunc a() {\n\n\tb(\n}\n\nfunc c() {\n}\n",
+				Changed: true,
+				Edits:   []serveEdit{{Start: 40, End: 41, Line: 8, Mode: "func", Node: "*ast.FuncDecl"}},
+				Diagnostics: []serveDiagnostic{
+					{Severity: "warning", Line: 5, Column: 1, Message: "declaration was not cleaned: expected operand, found '}'"},
+				},
+			},
+		},
+		{
+			`{"source": "package a\nfunc a( {\n}\n", "filename": "a.go"}`,
+			http.StatusUnprocessableEntity,
+			serveResponse{
+				Edits: []serveEdit{},
+				Diagnostics: []serveDiagnostic{
+					{Severity: "error", Line: 2, Column: 9, Message: "expected ')', found '{'"},
+					{Severity: "error", Line: 3, Column: 1, Message: "missing ',' in parameter list"},
+				},
+				Error: "a.go:2:9: expected ')', found '{' (and 1 more errors)",
+			},
+		},
+		{
+			`{"source": "package a", "mode": "nope"}`,
+			http.StatusBadRequest,
+			serveResponse{
+				Edits:       []serveEdit{},
+				Diagnostics: []serveDiagnostic{},
+				Error:       "invalid request: unknown mode `nope'",
+			},
+		},
+		{
+			`{"src": "package a"}`,
+			http.StatusBadRequest,
+			serveResponse{
+				Edits:       []serveEdit{},
+				Diagnostics: []serveDiagnostic{},
+				Error:       `invalid request: json: unknown field "src"`,
+			},
+		},
+		{
+			`{"source": "` + strings.Repeat("a", 2048) + `"}`,
+			http.StatusRequestEntityTooLarge,
+			serveResponse{
+				Edits:       []serveEdit{},
+				Diagnostics: []serveDiagnostic{},
+				Error:       "request is larger than 1024 bytes",
+			},
+		},
+	}
+
+	for i, test := range tests {
+		status, resp := post(t, h, test.body)
+		require.Equal(t, test.status, status, "Test %d failed", i)
+		require.Equal(t, test.expected, resp, "Test %d failed", i)
+	}
+
+	// requests are cleaned concurrently
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status, resp := post(t, h, tests[0].body)
+			require.Equal(t, http.StatusOK, status)
+			require.Equal(t, tests[0].expected, resp)
+		}()
+	}
+	wg.Wait()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/clean", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	require.Equal(t, http.MethodPost, rec.Header().Get("Allow"))
+}
+
+func TestServeTimeout(t *testing.T) {
+	// every pass of this source parses the whole source, cleaning it takes seconds
+	src := "package a\nfunc a() {\n" + strings.Repeat("\n", 1000) + strings.Repeat("\tx()\n", 5000) + "}\n"
+	body, err := json.Marshal(serveRequest{Source: src})
+	require.NoError(t, err)
+
+	stopped := make(chan error, 1)
+	s := newServer(goremovelines.AllMode, 1<<20, 50*time.Millisecond, 0)
+	s.clean = func(ctx context.Context, src string, opts goremovelines.Options) (*goremovelines.Result, error) {
+		result, err := goremovelines.CleanContext(ctx, src, opts)
+		stopped <- err
+		return result, err
+	}
+
+	status, resp := post(t, s.handler(), string(body))
+	require.Equal(t, http.StatusServiceUnavailable, status)
+	require.Equal(t, "timeout", resp.Error)
+
+	// the cleaning of the timed out request is stopped
+	select {
+	case err := <-stopped:
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-time.After(time.Second):
+		t.Fatal("the cleaning was not stopped")
+	}
+}
+
+func TestServeMaxConcurrent(t *testing.T) {
+	var calls atomic.Int32
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	s := newServer(goremovelines.AllMode, 1024, 100*time.Millisecond, 1)
+	s.clean = func(ctx context.Context, src string, opts goremovelines.Options) (*goremovelines.Result, error) {
+		calls.Add(1)
+		started <- struct{}{}
+		// the first request keeps its slot until both requests were answered
+		<-release
+		return nil, ctx.Err()
+	}
+	h := s.handler()
+
+	// the second request waits for the slot of the first one and times out without being cleaned
+	statuses := make(chan int, 2)
+	go func() {
+		status, _ := post(t, h, `{"source": "package a\n"}`)
+		statuses <- status
+	}()
+	<-started
+	go func() {
+		status, _ := post(t, h, `{"source": "package b\n"}`)
+		statuses <- status
+	}()
+	require.Equal(t, http.StatusServiceUnavailable, <-statuses)
+	require.Equal(t, http.StatusServiceUnavailable, <-statuses)
+	require.Equal(t, int32(1), calls.Load())
+	close(release)
+
+	// the slots are released again
+	s.clean = goremovelines.CleanContext
+	status, resp := post(t, s.handler(), `{"source": "package a\nfunc a() {\n\n}\n"}`)
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "package a\nfunc a() {\n}\n", resp.Source)
+}
+
+func TestServeShutdown(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s := newServer(goremovelines.AllMode, 1024, time.Minute, 0)
+	s.clean = func(ctx context.Context, src string, opts goremovelines.Options) (*goremovelines.Result, error) {
+		close(started)
+		<-release
+		return goremovelines.CleanContext(ctx, src, opts)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.serve(ctx, ln)
+	}()
+
+	type result struct {
+		resp *http.Response
+		err  error
+	}
+	requestDone := make(chan result, 1)
+	go func() {
+		resp, err := http.Post("http://"+ln.Addr().String()+"/clean", "application/json", //nolint:noctx // test request
+			strings.NewReader(`{"source": "package a\nfunc a() {\n\n}\n"}`))
+		requestDone <- result{resp, err}
+	}()
+
+	// the running request is finished before the server stops
+	<-started
+	cancel()
+	select {
+	case err := <-done:
+		t.Fatalf("server stopped before the request was finished: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+
+	r := <-requestDone
+	require.NoError(t, r.err)
+	defer r.resp.Body.Close()
+	require.Equal(t, http.StatusOK, r.resp.StatusCode)
+	var resp serveResponse
+	require.NoError(t, json.NewDecoder(r.resp.Body).Decode(&resp))
+	require.Equal(t, "package a\nfunc a() {\n}\n", resp.Source)
+	require.NoError(t, <-done)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Eun/goremovelines"
+)
+
+// statistic formats.
+const (
+	statsText = "text"
+	statsJSON = "json"
+)
+
+var statsFormats = []string{statsText, statsJSON}
+
+// fileStats are the removals of a single file.
+type fileStats struct {
+	Path     string         `json:"path"`
+	Removed  int            `json:"removed"`
+	Modes    map[string]int `json:"modes"`
+	Duration time.Duration  `json:"duration_ns"`
+}
+
+// dirStats are the removals of all files in a directory (without its subdirectories).
+type dirStats struct {
+	Path    string         `json:"path"`
+	Files   int            `json:"files"`
+	Removed int            `json:"removed"`
+	Modes   map[string]int `json:"modes"`
+}
+
+// stats aggregates the lines removed by a run per mode, construct, directory and file.
+// A nil stats records nothing.
+type stats struct {
+	Files       int            `json:"files"`
+	Changed     int            `json:"changed"`
+	Removed     int            `json:"removed"`
+	Duration    time.Duration  `json:"duration_ns"`
+	Modes       map[string]int `json:"by_mode"`
+	Constructs  map[string]int `json:"by_construct"`
+	Directories []*dirStats    `json:"by_directory"`
+	FileList    []*fileStats   `json:"by_file"`
+
+	dirs map[string]*dirStats
+}
+
+func newStats() *stats {
+	return &stats{
+		Modes:      make(map[string]int),
+		Constructs: make(map[string]int),
+		dirs:       make(map[string]*dirStats),
+	}
+}
+
+// add records the removals of the file path that took d to clean.
+func (s *stats) add(path string, removed []goremovelines.Removal, d time.Duration) {
+	if s == nil {
+		return
+	}
+	dir, ok := s.dirs[filepath.Dir(path)]
+	if !ok {
+		dir = &dirStats{Path: filepath.Dir(path), Modes: make(map[string]int)}
+		s.dirs[dir.Path] = dir
+		s.Directories = append(s.Directories, dir)
+	}
+	file := &fileStats{Path: path, Removed: len(removed), Modes: make(map[string]int), Duration: d}
+	s.FileList = append(s.FileList, file)
+
+	s.Files++
+	dir.Files++
+	if len(removed) > 0 {
+		s.Changed++
+	}
+	s.Removed += len(removed)
+	dir.Removed += len(removed)
+	s.Duration += d
+	for _, r := range removed {
+		mode := r.Mode.String()
+		s.Modes[mode]++
+		dir.Modes[mode]++
+		file.Modes[mode]++
+		s.Constructs[r.Node]++
+	}
+}
+
+// write writes the statistics in the format to w, directories and files with the most removals come first.
+func (s *stats) write(w io.Writer, format string) error {
+	if s == nil {
+		return nil
+	}
+	sort.SliceStable(s.Directories, func(i, j int) bool {
+		a, b := s.Directories[i], s.Directories[j]
+		if a.Removed != b.Removed {
+			return a.Removed > b.Removed
+		}
+		return a.Path < b.Path
+	})
+	sort.SliceStable(s.FileList, func(i, j int) bool {
+		a, b := s.FileList[i], s.FileList[j]
+		if a.Removed != b.Removed {
+			return a.Removed > b.Removed
+		}
+		return a.Path < b.Path
+	})
+
+	if format == statsJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "\t")
+		return enc.Encode(s)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Files:\t%d\n", s.Files)
+	fmt.Fprintf(tw, "Changed files:\t%d\n", s.Changed)
+	fmt.Fprintf(tw, "Removed lines:\t%d\n", s.Removed)
+	fmt.Fprintf(tw, "Time:\t%s\n", s.Duration.Round(time.Microsecond))
+
+	fmt.Fprintf(tw, "\nMode\tLines\n")
+	for _, mode := range sortedKeys(s.Modes) {
+		fmt.Fprintf(tw, "%s\t%d\n", mode, s.Modes[mode])
+	}
+	fmt.Fprintf(tw, "\nConstruct\tLines\n")
+	for _, node := range sortedKeys(s.Constructs) {
+		fmt.Fprintf(tw, "%s\t%d\n", node, s.Constructs[node])
+	}
+	fmt.Fprintf(tw, "\nDirectory\tFiles\tLines\n")
+	for _, dir := range s.Directories {
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", dir.Path, dir.Files, dir.Removed)
+	}
+	fmt.Fprintf(tw, "\nFile\tLines\tTime\n")
+	for _, file := range s.FileList {
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", file.Path, file.Removed, file.Duration.Round(time.Microsecond))
+	}
+	return tw.Flush()
+}
+
+// sortedKeys returns the keys of m, the keys with the highest values come first.
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if m[keys[i]] != m[keys[j]] {
+			return m[keys[i]] > m[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
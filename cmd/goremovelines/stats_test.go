@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Eun/goremovelines"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	s := newStats()
+	s.add("a/b.go", []goremovelines.Removal{
+		{Line: 3, Mode: goremovelines.FuncMode, Node: "*ast.FuncDecl"},
+		{Line: 7, Mode: goremovelines.ForMode, Node: "*ast.RangeStmt"},
+	}, 2*time.Millisecond)
+	s.add("a/c.go", nil, time.Millisecond)
+	s.add("d/e.go", []goremovelines.Removal{
+		{Line: 3, Mode: goremovelines.FuncMode, Node: "*ast.FuncLit"},
+		{Line: 4, Mode: goremovelines.FuncMode, Node: "*ast.FuncLit"},
+		{Line: 9, Mode: goremovelines.StructMode, Node: "*ast.StructType"},
+	}, 3*time.Millisecond)
+
+	var buf bytes.Buffer
+	require.NoError(t, s.write(&buf, statsText))
+	require.Equal(t, `Files:          3
+Changed files:  2
+Removed lines:  5
+Time:           6ms
+
+Mode    Lines
+func    3
+for     1
+struct  1
+
+Construct        Lines
+*ast.FuncLit     2
+*ast.FuncDecl    1
+*ast.RangeStmt   1
+*ast.StructType  1
+
+Directory  Files  Lines
+d          1      3
+a          2      2
+
+File    Lines  Time
+d/e.go  3      3ms
+a/b.go  2      2ms
+a/c.go  0      1ms
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, s.write(&buf, statsJSON))
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	require.Equal(t, float64(5), decoded["removed"])
+	require.Equal(t, map[string]interface{}{"func": float64(3), "for": float64(1), "struct": float64(1)}, decoded["by_mode"])
+	require.Equal(t, "d/e.go", decoded["by_file"].([]interface{})[0].(map[string]interface{})["path"])
+
+	var nilStats *stats
+	nilStats.add("a.go", nil, 0)
+	require.NoError(t, nilStats.write(&buf, statsText))
+}
@@ -2,67 +2,76 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
-	"sort"
 	"strings"
-)
 
-// source taken from gometalinter
+	"github.com/Eun/goremovelines/walker"
+)
 
-func resolvePaths(paths, skip []string) []string {
-	if len(paths) == 0 {
-		return []string{"."}
+// newWalker returns a walker that uses the filters of the flags and the configs.
+func newWalker(configs *configLoader) *walker.Walker {
+	w := walker.New()
+	w.FilterFor = func(dir string) (walker.Filter, error) {
+		s, err := configs.settingsFor(dir)
+		return s.filter, err
 	}
+	w.IgnoreFiles = *ignoreFilesFlag
+	w.Tags = tags()
+	w.Debugf = debugf
+	w.Warnf = warningf
+	return w
+}
 
-	skipPath := newPathFilter(skip)
-	files := newStringSet()
-	for _, path := range paths {
-		if strings.HasSuffix(path, "/...") {
-			root := filepath.Dir(path)
-			_ = filepath.Walk(root, func(p string, i os.FileInfo, err error) error {
-				if err != nil {
-					warningf("invalid path %q: %s", p, err)
-					return err
-				}
-
-				skip := skipPath(p)
-				switch {
-				case i.IsDir() && skip:
-					return filepath.SkipDir
-				case !i.IsDir() && !skip && strings.HasSuffix(p, ".go"):
-					files.add(filepath.Clean(p))
-				}
-				return nil
-			})
-		} else {
-			files.add(filepath.Clean(path))
-		}
-	}
-	out := make([]string, 0, files.size())
-	for _, d := range files.asSlice() {
-		out = append(out, relativePackagePath(d))
+func resolvePaths(paths []string, configs *configLoader) ([]string, error) {
+	out, err := newWalker(configs).Resolve(paths)
+	if err != nil {
+		return nil, err
 	}
-	sort.Strings(out)
 	for _, d := range out {
 		debugf("linting path %s", d)
 	}
-	return out
+	return out, nil
 }
 
-func newPathFilter(skip []string) func(string) bool {
-	filter := map[string]bool{}
-	for _, name := range skip {
-		filter[name] = true
+// readFileList reads the list of files from name (or stdin if name is -),
+// entries are separated by newlines or by NUL characters if null is set.
+func readFileList(name string, null bool) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open `%s': %w", name, err)
+		}
+		defer f.Close()
+		r = f
+	}
+	buf, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read `%s': %w", name, err)
 	}
 
-	return func(path string) bool {
-		base := filepath.Base(path)
-		if filter[base] || filter[path] {
-			return true
+	sep := "\n"
+	if null {
+		sep = "\x00"
+	}
+	var files []string
+	for _, entry := range strings.Split(string(buf), sep) {
+		if !null {
+			entry = strings.TrimSuffix(entry, "\r")
+		}
+		if entry != "" {
+			files = append(files, entry)
 		}
-		return base != "." && base != ".." && strings.ContainsAny(base[0:1], "_.")
 	}
+	return files, nil
+}
+
+func tags() []string {
+	if *tagsFlag == "" {
+		return nil
+	}
+	return strings.Split(*tagsFlag, ",")
 }
 
 func debugf(format string, args ...interface{}) {
@@ -74,11 +83,3 @@ func debugf(format string, args ...interface{}) {
 func warningf(format string, args ...interface{}) {
 	fmt.Fprintf(os.Stderr, "WARNING: "+format+"\n", args...)
 }
-
-func relativePackagePath(dir string) string {
-	if filepath.IsAbs(dir) || strings.HasPrefix(dir, ".") {
-		return dir
-	}
-	// package names must start with a ./
-	return "./" + dir
-}
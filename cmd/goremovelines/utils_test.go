@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// setFlag sets the flag to value until the end of the test.
+func setFlag[T any](t *testing.T, flag *T, value T) {
+	t.Helper()
+	old := *flag
+	*flag = value
+	t.Cleanup(func() {
+		*flag = old
+	})
+}
+
+// withStdin runs fn with os.Stdin reading content.
+func withStdin(t *testing.T, content string, fn func()) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "stdin")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	stdin := os.Stdin
+	os.Stdin = f
+	defer func() {
+		os.Stdin = stdin
+	}()
+	fn()
+}
+
+// captureStdout runs fn and returns what it wrote to os.Stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "stdout")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	stdout := os.Stdout
+	os.Stdout = f
+	func() {
+		defer func() {
+			os.Stdout = stdout
+		}()
+		fn()
+	}()
+	_, err = f.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	buf, err := io.ReadAll(f)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	return string(buf)
+}
+
+func TestReadFileList(t *testing.T) {
+	tests := []struct {
+		content  string
+		null     bool
+		expected []string
+	}{
+		{"a.go\nb.go\n", false, []string{"a.go", "b.go"}},
+		{"a.go\nb.go", false, []string{"a.go", "b.go"}},
+		{"a.go\r\nb.go\r\n", false, []string{"a.go", "b.go"}},
+		{"\na.go\n\n\nb.go\n\n", false, []string{"a.go", "b.go"}},
+		{"a b.go\n", false, []string{"a b.go"}},
+		{"a.go\x00b\n.go\x00", true, []string{"a.go", "b\n.go"}},
+		{"a.go\x00\x00b.go", true, []string{"a.go", "b.go"}},
+		{"a.go\r\x00", true, []string{"a.go\r"}},
+		{"", false, nil},
+		{"\x00", true, nil},
+	}
+
+	for i, test := range tests {
+		path := filepath.Join(t.TempDir(), "files")
+		require.NoError(t, os.WriteFile(path, []byte(test.content), 0o600))
+		files, err := readFileList(path, test.null)
+		require.NoError(t, err, "Test %d failed", i)
+		require.Equal(t, test.expected, files, "Test %d failed", i)
+
+		withStdin(t, test.content, func() {
+			files, err = readFileList("-", test.null)
+		})
+		require.NoError(t, err, "Test %d failed", i)
+		require.Equal(t, test.expected, files, "Test %d failed", i)
+	}
+
+	_, err := readFileList(filepath.Join(t.TempDir(), "missing"), false)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestReadFileListMissingFile(t *testing.T) {
+	setFlag(t, keepGoingFlag, true)
+	setFlag(t, removeLineFlag, []string{"all"})
+
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.go")
+	missing := filepath.Join(dir, "missing.go")
+	require.NoError(t, os.WriteFile(a, []byte("package a\nfunc a() {\n\n}\n"), 0o600))
+	list := filepath.Join(dir, "files")
+	require.NoError(t, os.WriteFile(list, []byte(missing+"\n"+a+"\n"), 0o600))
+
+	files, err := readFileList(list, false)
+	require.NoError(t, err)
+	var cleanErr error
+	out := captureStdout(t, func() {
+		cleanErr = cleanPaths(files, nil)
+	})
+	require.EqualError(t, cleanErr, fmt.Sprintf("1 of 2 file(s) could not be cleaned:\n"+
+		"unable to read file `%s': open %s: no such file or directory", missing, missing))
+	require.Equal(t, "-- "+a+" --\npackage a\nfunc a() {\n}\n", out)
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/Eun/goremovelines"
+	"github.com/Eun/goremovelines/walker"
+)
+
+// watchedFile is the state of a file that is watched.
+type watchedFile struct {
+	modTime time.Time
+	size    int64
+	// hash is the hash of the content that was cleaned or written last, it is empty if the file was not cleaned yet.
+	hash string
+	// changed is the time the last change was noticed, it is zero if no change is pending.
+	changed time.Time
+}
+
+// watcher polls the files of the patterns and cleans every file that changed.
+// A file is cleaned once it did not change for the debounce duration,
+// files that are created in the watched directories are picked up by the next poll.
+type watcher struct {
+	run      *run
+	walker   *walker.Walker
+	patterns []string
+	// write rewrites the changed files, otherwise only the paths of files that are not clean are reported.
+	write    bool
+	debounce time.Duration
+	// out receives the paths of the files that were (or would be) rewritten.
+	out io.Writer
+
+	files   map[string]*watchedFile
+	started bool
+}
+
+func newWatcher(r *run, patterns []string) *watcher {
+	return &watcher{
+		run:      r,
+		walker:   newWalker(r.configs),
+		patterns: patterns,
+		out:      os.Stdout,
+		files:    make(map[string]*watchedFile),
+	}
+}
+
+// watch polls the files every interval until ctx is done.
+// The files that exist when watching starts are not cleaned until they change.
+func (w *watcher) watch(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid interval `%s': must be positive", interval)
+	}
+	if err := w.poll(time.Now()); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			if err := w.poll(now); err != nil {
+				warningf("Unable to resolve paths: %v", err)
+			}
+		}
+	}
+}
+
+// poll looks for changed, new and removed files and cleans the files whose last change is older than the debounce duration.
+func (w *watcher) poll(now time.Time) error {
+	paths, err := w.walker.Resolve(w.patterns)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			// removed since the walk
+			continue
+		}
+		seen[path] = true
+		f, ok := w.files[path]
+		if !ok {
+			f = &watchedFile{modTime: info.ModTime(), size: info.Size()}
+			w.files[path] = f
+			if w.started {
+				debugf("new file %s", path)
+				f.changed = now
+			}
+			continue
+		}
+		if !info.ModTime().Equal(f.modTime) || info.Size() != f.size {
+			debugf("%s changed", path)
+			f.modTime = info.ModTime()
+			f.size = info.Size()
+			f.changed = now
+		}
+	}
+	for path := range w.files {
+		if !seen[path] {
+			debugf("%s was removed", path)
+			delete(w.files, path)
+		}
+	}
+	w.started = true
+
+	for _, path := range paths {
+		f, ok := w.files[path]
+		if !ok || f.changed.IsZero() || now.Sub(f.changed) < w.debounce {
+			continue
+		}
+		f.changed = time.Time{}
+		if err := w.clean(path, f); err != nil {
+			warningf("%v", err)
+		}
+	}
+	return nil
+}
+
+// clean cleans the file, contents that were cleaned or written before (e.g. our own writes) are skipped.
+func (w *watcher) clean(path string, f *watchedFile) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("unable to read file `%s': %w", path, err)
+	}
+	hash := hashContent(src)
+	if hash == f.hash {
+		debugf("content of %s did not change", path)
+		return nil
+	}
+	f.hash = hash
+
+	s, err := w.run.configs.settingsFor(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	if !s.includeGenerated && goremovelines.IsGenerated(string(src)) {
+		debugf("skipping generated file %s", path)
+		return nil
+	}
+	out, err := w.run.clean(path, src, s)
+	if err != nil {
+		return cleanError(path, err)
+	}
+	if bytes.Equal(src, out) {
+		return nil
+	}
+
+	if w.write {
+		// every rewrite is a run of its own, so undo restores the last rewritten file
+		j, err := newJournal()
+		if err != nil {
+			return err
+		}
+		if err := writeSource(path, src, out, j, *backupFlag); err != nil {
+			return err
+		}
+		if err := j.save(); err != nil {
+			return err
+		}
+		// remember our own write, so it is not noticed as a change
+		f.hash = hashContent(out)
+		if info, err := os.Stat(path); err == nil {
+			f.modTime = info.ModTime()
+			f.size = info.Size()
+		}
+	}
+	_, err = fmt.Fprintln(w.out, path)
+	return err
+}
+
+// watchPaths watches the paths until the process is interrupted.
+func watchPaths(paths []string, configs *configLoader) error {
+	r, err := newRun(configs)
+	if err != nil {
+		return err
+	}
+	w := newWatcher(r, paths)
+	w.write = *writeToSourceFlag
+	w.debounce = *watchDebounceFlag
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return w.watch(ctx, *watchIntervalFlag)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatcher(t *testing.T) {
+	t.Setenv("GOREMOVELINES_STATE_DIR", t.TempDir())
+	remove := *removeLineFlag
+	*removeLineFlag = []string{"all"}
+	defer func() {
+		*removeLineFlag = remove
+	}()
+
+	const clean = "package main\nfunc main() {\n}\n"
+	const dirty = "package main\nfunc main() {\n\n\n}\n"
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.go")
+	b := filepath.Join(dir, "b.go")
+	require.NoError(t, os.WriteFile(a, []byte(dirty), 0o600))
+
+	var out bytes.Buffer
+	w := newWatcher(&run{}, []string{dir + "/..."})
+	w.write = true
+	w.debounce = time.Second
+	w.out = &out
+	start := time.Now()
+	poll := func(after time.Duration) {
+		t.Helper()
+		require.NoError(t, w.poll(start.Add(after)))
+	}
+	requireContent := func(path, expected string) {
+		t.Helper()
+		buf, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, expected, string(buf))
+	}
+
+	// existing files are not cleaned until they change
+	poll(0)
+	requireContent(a, dirty)
+
+	const dirtier = "package main\nfunc main() {\n\n\n\n}\n"
+	require.NoError(t, os.WriteFile(a, []byte(dirtier), 0o600))
+	poll(100 * time.Millisecond)
+	requireContent(a, dirtier)
+	poll(2 * time.Second)
+	requireContent(a, clean)
+	require.Equal(t, a+"\n", out.String())
+
+	// our own write is not a change
+	poll(4 * time.Second)
+	require.Equal(t, a+"\n", out.String())
+
+	// new files are picked up
+	require.NoError(t, os.WriteFile(b, []byte(dirty), 0o600))
+	poll(5 * time.Second)
+	requireContent(b, dirty)
+	poll(7 * time.Second)
+	requireContent(b, clean)
+	require.Equal(t, a+"\n"+b+"\n", out.String())
+
+	// without write the files are only reported
+	w.write = false
+	out.Reset()
+	require.NoError(t, os.WriteFile(a, []byte(dirty), 0o600))
+	poll(8 * time.Second)
+	poll(10 * time.Second)
+	requireContent(a, dirty)
+	require.Equal(t, a+"\n", out.String())
+
+	// files with syntax errors are reported as warnings, watching goes on
+	require.NoError(t, os.WriteFile(b, []byte("package main\nfunc {"), 0o600))
+	poll(11 * time.Second)
+	poll(13 * time.Second)
+	require.Equal(t, a+"\n", out.String())
+}
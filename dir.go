@@ -0,0 +1,49 @@
+package goremovelines
+
+import (
+	"os"
+
+	"github.com/Eun/goremovelines/walker"
+	"github.com/pkg/errors"
+)
+
+// CleanDir cleans all go files in dir and its subdirectories with the specific mode and writes them back.
+// Like the command line tool it skips hidden paths, paths that are ignored by ignore files, testdata and vendor
+// directories, nested modules and generated files (see walker.New). It returns the paths of the modified files.
+func CleanDir(dir string, mode Mode) ([]string, error) {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Unable to stat `%s'", dir)
+	}
+	if !fi.IsDir() {
+		return nil, errors.Errorf("`%s' is not a directory", dir)
+	}
+	var modified []string
+	err = walker.New().Walk(dir+"/...", func(path string) error {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return errors.Wrapf(err, "Unable to stat `%s'", path)
+		}
+		buf, err := os.ReadFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "Unable to read `%s'", path)
+		}
+		src := string(buf)
+		if IsGenerated(src) {
+			return nil
+		}
+
+		if _, err := clean(&src, &Options{Mode: mode, Filename: path}); err != nil {
+			return err
+		}
+		if src == string(buf) {
+			return nil
+		}
+		if err := os.WriteFile(path, []byte(src), fi.Mode().Perm()); err != nil {
+			return errors.Wrapf(err, "Unable to write `%s'", path)
+		}
+		modified = append(modified, path)
+		return nil
+	})
+	return modified, err
+}
@@ -0,0 +1,147 @@
+package goremovelines
+
+import (
+	"go/ast"
+	"go/token"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// directivePrefix is the prefix of all comment directives.
+//
+//	//goremovelines:ignore [modes]       do not clean the node on the next line
+//	//goremovelines:disable [modes]      do not clean nodes until the next enable directive
+//	//goremovelines:enable [modes]       clean nodes again
+//	//goremovelines:ignore-file [modes]  do not clean the file
+//
+// modes is an optional comma separated list of mode names (e.g. `case,if`), if omitted all modes are affected.
+const directivePrefix = "//goremovelines:"
+
+type directiveRegion struct {
+	start token.Pos
+	// mask contains the modes that are disabled from start until the next region.
+	mask Mode
+	// disable is set if the region was started by a disable directive.
+	disable bool
+	// mode contains the modes of the directive.
+	mode    Mode
+	comment *ast.Comment
+}
+
+// directiveComment is a directive and the modes it disables.
+type directiveComment struct {
+	comment *ast.Comment
+	mode    Mode
+}
+
+// directives contains the modes that are disabled by comment directives.
+type directives struct {
+	file    Mode
+	lines   map[int]Mode
+	regions []directiveRegion
+
+	// fileComments and lineComments are the directives of file and lines, they are used to explain decisions.
+	fileComments []directiveComment
+	lineComments map[int][]directiveComment
+}
+
+func parseDirectives(set *token.FileSet, file *ast.File) (*directives, error) {
+	d := &directives{
+		lines:        make(map[int]Mode),
+		lineComments: make(map[int][]directiveComment),
+	}
+	var disabled Mode
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			if !strings.HasPrefix(comment.Text, directivePrefix) {
+				continue
+			}
+			fields := strings.Fields(strings.TrimPrefix(comment.Text, directivePrefix))
+			line := set.Position(comment.Pos()).Line
+			if len(fields) == 0 || len(fields) > 2 {
+				return nil, errors.Errorf("Invalid directive `%s' at line %d", comment.Text, line)
+			}
+
+			mode := AllMode
+			if len(fields) == 2 {
+				var err error
+				mode, err = ParseMode(fields[1])
+				if err != nil {
+					return nil, errors.Errorf("Invalid directive `%s' at line %d: %v", comment.Text, line, err)
+				}
+			}
+
+			switch fields[0] {
+			case "ignore":
+				// the directive applies to the node that follows the comment group
+				next := set.Position(group.End()).Line + 1
+				d.lines[next] |= mode
+				d.lineComments[next] = append(d.lineComments[next], directiveComment{comment: comment, mode: mode})
+			case "disable":
+				disabled |= mode
+				d.regions = append(d.regions, directiveRegion{
+					start: comment.Pos(), mask: disabled, disable: true, mode: mode, comment: comment,
+				})
+			case "enable":
+				disabled &^= mode
+				d.regions = append(d.regions, directiveRegion{start: comment.Pos(), mask: disabled, mode: mode, comment: comment})
+			case "ignore-file":
+				d.file |= mode
+				d.fileComments = append(d.fileComments, directiveComment{comment: comment, mode: mode})
+			default:
+				return nil, errors.Errorf("Unknown directive `%s' at line %d", comment.Text, line)
+			}
+		}
+	}
+	return d, nil
+}
+
+// apply removes the modes that are disabled for the node from mode.
+func (d *directives) apply(set *token.FileSet, node ast.Node, mode Mode) Mode {
+	if d == nil {
+		return mode
+	}
+	mode &^= d.file
+	mode &^= d.lines[set.Position(node.Pos()).Line]
+
+	i := sort.Search(len(d.regions), func(i int) bool {
+		return d.regions[i].start > node.Pos()
+	})
+	if i > 0 {
+		mode &^= d.regions[i-1].mask
+	}
+	return mode
+}
+
+// disabledBy returns the directive that disables mode for the node, nil if mode is not disabled by a directive.
+func (d *directives) disabledBy(set *token.FileSet, node ast.Node, mode Mode) *ast.Comment {
+	if d == nil {
+		return nil
+	}
+	for _, c := range d.fileComments {
+		if c.mode&mode != 0 {
+			return c.comment
+		}
+	}
+	for _, c := range d.lineComments[set.Position(node.Pos()).Line] {
+		if c.mode&mode != 0 {
+			return c.comment
+		}
+	}
+
+	i := sort.Search(len(d.regions), func(i int) bool {
+		return d.regions[i].start > node.Pos()
+	})
+	if i == 0 || d.regions[i-1].mask&mode == 0 {
+		return nil
+	}
+	// the mode is disabled by the last disable directive that contains it
+	for j := i - 1; j >= 0; j-- {
+		if d.regions[j].disable && d.regions[j].mode&mode != 0 {
+			return d.regions[j].comment
+		}
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package goremovelines
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvalidDirectives(t *testing.T) {
+	tests := []string{
+		"package main\n\n//goremovelines:ignroe\nfunc main() {\n}\n",
+		"package main\n\n//goremovelines:ignore fucn\nfunc main() {\n}\n",
+		"package main\n\n//goremovelines:\nfunc main() {\n}\n",
+		"package main\n\n//goremovelines:ignore func if\nfunc main() {\n}\n",
+	}
+
+	for i, test := range tests {
+		var out bytes.Buffer
+		require.Error(t, CleanFile(test, &out, AllMode), "Test %d failed", i)
+	}
+}
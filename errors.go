@@ -0,0 +1,73 @@
+package goremovelines
+
+import (
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ParseError is returned if a source can not be parsed.
+// Use errors.As to access the positions of the syntax errors.
+type ParseError struct {
+	// Filename is the name of the parsed file, it is used for positions without a file name.
+	Filename string
+	// Errors are the syntax errors sorted by position.
+	Errors scanner.ErrorList
+}
+
+// Error returns the first syntax error in the form `file:line:col: message'.
+func (e *ParseError) Error() string {
+	switch len(e.Errors) {
+	case 0:
+		if e.Filename == "" {
+			return "Failed to parse source"
+		}
+		return fmt.Sprintf("Failed to parse `%s'", e.Filename)
+	case 1:
+		return e.format(e.Errors[0])
+	default:
+		return fmt.Sprintf("%s (and %d more errors)", e.format(e.Errors[0]), len(e.Errors)-1)
+	}
+}
+
+// Unwrap returns the underlying scanner.ErrorList.
+func (e *ParseError) Unwrap() error {
+	return e.Errors
+}
+
+func (e *ParseError) format(err *scanner.Error) string {
+	pos := err.Pos
+	if pos.Filename == "" {
+		pos.Filename = e.Filename
+	}
+	return strings.TrimPrefix(pos.String()+": "+err.Msg, "-: ")
+}
+
+// newParseError converts the error of the parser into a ParseError.
+func newParseError(filename string, err error) *ParseError {
+	var list scanner.ErrorList
+	if !errors.As(err, &list) {
+		list = scanner.ErrorList{{Msg: err.Error()}}
+	}
+	list.Sort()
+	return &ParseError{Filename: filename, Errors: list}
+}
+
+// VerifyError is returned if Options.Verify is set and the cleaned source is not equivalent to the original.
+type VerifyError struct {
+	// Pos is the position of the difference, it is not valid if it is unknown.
+	Pos token.Position
+	// Msg describes the difference.
+	Msg string
+}
+
+// Error returns the difference in the form `file:line:col: message'.
+func (e *VerifyError) Error() string {
+	if !e.Pos.IsValid() {
+		return "Verification failed: " + e.Msg
+	}
+	return "Verification failed at " + e.Pos.String() + ": " + e.Msg
+}
@@ -0,0 +1,52 @@
+package goremovelines
+
+import (
+	"bytes"
+	"errors"
+	"go/scanner"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{"package a\n\nfunc a( {\n}\n", "3:9: expected ')', found '{' (and 1 more errors)"},
+		{"package a\n\nvar = 1\n", "3:5: expected 'IDENT', found '='"},
+		{"func a() {}\n", "1:1: expected 'package', found 'func'"},
+	}
+
+	for i, test := range tests {
+		err := CleanFile(test.src, &bytes.Buffer{}, AllMode)
+		var parseErr *ParseError
+		require.True(t, errors.As(err, &parseErr), "Test %d failed", i)
+		require.Equal(t, test.expected, err.Error(), "Test %d failed", i)
+		require.NotContains(t, err.Error(), "package a", "Test %d failed", i)
+
+		var list scanner.ErrorList
+		require.True(t, errors.As(err, &list), "Test %d failed", i)
+		require.Equal(t, parseErr.Errors, list, "Test %d failed", i)
+
+		parseErr.Filename = "a.go"
+		require.Equal(t, "a.go:"+test.expected, err.Error(), "Test %d failed", i)
+	}
+}
+
+func TestCleanFilePathErrors(t *testing.T) {
+	dir := t.TempDir()
+	err := CleanFilePath(filepath.Join(dir, "missing.go"), &bytes.Buffer{}, AllMode)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	path := filepath.Join(dir, "a.go")
+	require.NoError(t, os.WriteFile(path, []byte("package a\n\nfunc a() {\n"), 0o600))
+	err = CleanFilePath(path, &bytes.Buffer{}, AllMode)
+	var parseErr *ParseError
+	require.True(t, errors.As(err, &parseErr))
+	require.Equal(t, path, parseErr.Filename)
+	require.Equal(t, path+":3:12: expected '}', found 'EOF'", err.Error())
+}
@@ -0,0 +1,219 @@
+package goremovelines
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Explanation explains why a line is removed or kept, see Explain.
+type Explanation struct {
+	// Line is the explained line.
+	Line int
+	// Blank reports whether the line is blank, only blank lines are removed.
+	Blank bool
+	// Skipped reports whether the line is part of a declaration with syntax errors that was skipped in tolerant mode.
+	Skipped bool
+	// Removed reports whether the line is removed with the options.
+	Removed bool
+	// Mode is the mode that removes the line (or would remove it if it was enabled and not disabled by a directive),
+	// it is 0 if the line is never removed, e.g. because it is not at the start or the end of a body.
+	Mode Mode
+	// Node is the type of the construct whose body starts or ends with the line (e.g. *ast.FuncDecl)
+	// and NodePos its position, Node is empty if Mode is 0.
+	Node    string
+	NodePos token.Position
+	// Path are the types of the nodes that enclose the line, the innermost node first.
+	Path []string
+	// Directive is the comment directive that keeps the line and DirectivePos its position,
+	// it is empty if the line is not kept by a directive.
+	Directive    string
+	DirectivePos token.Position
+}
+
+// Explain explains why the line (starting at 1) of src is removed or kept when it is cleaned with the options.
+// Options.Gofmt and Options.Verify are ignored.
+func Explain(src string, line int, opts Options) (*Explanation, error) {
+	lines := strings.Split(strings.TrimSuffix(src, "\n"), "\n")
+	if line < 1 || line > len(lines) {
+		return nil, errors.Errorf("Line %d is out of range (1-%d)", line, len(lines))
+	}
+	opts.Gofmt = false
+	opts.Verify = false
+	e := &Explanation{
+		Line:  line,
+		Blank: strings.TrimSpace(lines[line-1]) == "",
+	}
+
+	// the removals of the options also report syntax errors with the positions of fragments
+	removal, skipped, err := removalOf(src, line, &opts)
+	if err != nil {
+		return nil, err
+	}
+	t, err := parseForExplain(src, &opts)
+	if err != nil {
+		return nil, err
+	}
+	path := t.enclosing(line)
+	for _, n := range path {
+		e.Path = append(e.Path, fmt.Sprintf("%T", n))
+	}
+	e.Skipped = skipped
+	if !e.Blank || skipped {
+		return e, nil
+	}
+
+	if removal != nil {
+		e.Removed = true
+	} else {
+		// clean with all modes and without directives to find the mode that would remove the line
+		all := opts
+		all.Mode = AllMode
+		all.ignoreDirectives = true
+		removal, _, err = removalOf(src, line, &all)
+		if err != nil {
+			return nil, err
+		}
+		if removal == nil {
+			return e, nil
+		}
+	}
+	e.Mode = removal.Mode
+	e.Node = removal.Node
+
+	for i, n := range path {
+		if fmt.Sprintf("%T", n) != removal.Node {
+			continue
+		}
+		e.NodePos = t.position(n.Pos())
+		if e.Removed || opts.Mode&e.Mode == 0 {
+			break
+		}
+		// directives of the node and of all nodes that enclose it apply
+		for _, parent := range path[i:] {
+			if c := t.directives.disabledBy(t.set, parent, e.Mode); c != nil {
+				e.Directive = c.Text
+				e.DirectivePos = t.position(c.Pos())
+				break
+			}
+		}
+		break
+	}
+	return e, nil
+}
+
+// removalOf cleans src and returns the removal of the line (nil if it is not removed)
+// and whether the line is part of a skipped declaration.
+func removalOf(src string, line int, opts *Options) (*Removal, bool, error) {
+	result, err := clean(&src, opts)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, d := range result.Skipped {
+		if d.Pos.Line <= line && line <= d.End.Line {
+			return nil, true, nil
+		}
+	}
+	for i := range result.Removed {
+		if result.Removed[i].Line == line {
+			return &result.Removed[i], false, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// explainTree is the parsed source of Explain.
+type explainTree struct {
+	set        *token.FileSet
+	file       *ast.File
+	directives *directives
+	// fragment is the wrapper of the source, nil if the source is a complete file.
+	fragment *fragment
+}
+
+func parseForExplain(src string, opts *Options) (*explainTree, error) {
+	t := &explainTree{}
+	if opts.Fragment {
+		t.fragment = detectFragment(src, opts.Filename)
+		if t.fragment != nil {
+			src = t.fragment.prefix + src + t.fragment.suffix
+		}
+	}
+	var err error
+	t.set, t.file, _, err = parseForVerify(src, opts)
+	if err != nil {
+		return nil, err
+	}
+	t.directives, err = parseDirectives(t.set, t.file)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// position returns the position of pos in the source (not in the wrapped fragment).
+func (t *explainTree) position(pos token.Pos) token.Position {
+	p := t.set.Position(pos)
+	if t.fragment != nil {
+		p = t.fragment.position(p)
+	}
+	return p
+}
+
+// enclosing returns the deepest chain of nodes that enclose the line, the innermost node first.
+// The file and the nodes of the fragment wrapper are not part of the result.
+func (t *explainTree) enclosing(line int) []ast.Node {
+	if t.fragment != nil {
+		line++
+	}
+	// case clauses end at the next clause, so trailing blank lines are part of them
+	ends := make(map[ast.Node]token.Pos)
+	var stack, path []ast.Node
+	ast.Inspect(t.file, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return false
+		}
+		if block, ok := n.(*ast.BlockStmt); ok {
+			for i, stmt := range block.List {
+				switch stmt.(type) {
+				case *ast.CaseClause, *ast.CommClause:
+					end := block.Rbrace
+					if i+1 < len(block.List) {
+						end = block.List[i+1].Pos()
+					}
+					ends[stmt] = end
+				}
+			}
+		}
+		end, ok := ends[n]
+		if !ok {
+			end = n.End()
+		}
+		start := t.set.Position(n.Pos())
+		if start.Line > line || t.set.Position(end).Line < line {
+			return false
+		}
+		stack = append(stack, n)
+		if len(stack) > len(path) {
+			path = append(path[:0], stack...)
+		}
+		return true
+	})
+
+	chain := make([]ast.Node, 0, len(path))
+	for i := len(path) - 1; i >= 0; i-- {
+		n := path[i]
+		if _, ok := n.(*ast.File); ok {
+			continue
+		}
+		if t.fragment != nil && t.set.Position(n.Pos()).Offset < len(t.fragment.prefix) {
+			continue
+		}
+		chain = append(chain, n)
+	}
+	return chain
+}
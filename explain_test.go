@@ -0,0 +1,124 @@
+package goremovelines
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplain(t *testing.T) {
+	const src = `package a
+
+func a() {
+
+	f := func() {
+		b()
+
+	}
+	switch {
+	case true:
+
+		b()
+	}
+	//goremovelines:disable if
+	if true {
+
+	}
+	//goremovelines:enable
+}
+`
+
+	tests := []struct {
+		line      int
+		mode      Mode
+		expected  Explanation
+		directive string
+	}{
+		{
+			line: 4,
+			mode: AllMode,
+			expected: Explanation{
+				Line: 4, Blank: true, Removed: true, Mode: FuncMode, Node: "*ast.FuncDecl",
+				Path: []string{"*ast.BlockStmt", "*ast.FuncDecl"},
+			},
+		},
+		{
+			line: 7,
+			mode: AllMode,
+			expected: Explanation{
+				Line: 7, Blank: true, Removed: true, Mode: FuncMode, Node: "*ast.FuncLit",
+				Path: []string{"*ast.BlockStmt", "*ast.FuncLit", "*ast.AssignStmt", "*ast.BlockStmt", "*ast.FuncDecl"},
+			},
+		},
+		{
+			line: 11,
+			mode: AllMode &^ CaseMode,
+			expected: Explanation{
+				Line: 11, Blank: true, Mode: CaseMode, Node: "*ast.CaseClause",
+				Path: []string{"*ast.CaseClause", "*ast.BlockStmt", "*ast.SwitchStmt", "*ast.BlockStmt", "*ast.FuncDecl"},
+			},
+		},
+		{
+			line: 16,
+			mode: AllMode,
+			expected: Explanation{
+				Line: 16, Blank: true, Mode: IfMode, Node: "*ast.IfStmt",
+				Path:      []string{"*ast.BlockStmt", "*ast.IfStmt", "*ast.BlockStmt", "*ast.FuncDecl"},
+				Directive: "//goremovelines:disable if",
+			},
+			directive: "a.go:14:2",
+		},
+		{
+			line: 5,
+			mode: AllMode,
+			expected: Explanation{
+				Line: 5,
+				Path: []string{"*ast.FieldList", "*ast.FuncType", "*ast.FuncLit", "*ast.AssignStmt", "*ast.BlockStmt", "*ast.FuncDecl"},
+			},
+		},
+		{
+			line: 2,
+			mode: AllMode,
+			expected: Explanation{
+				Line: 2, Blank: true,
+			},
+		},
+	}
+
+	for i, test := range tests {
+		e, err := Explain(src, test.line, Options{Mode: test.mode, Filename: "a.go"})
+		require.NoError(t, err, "Test %d failed", i)
+		if test.expected.Node != "" {
+			require.Equal(t, "a.go", e.NodePos.Filename, "Test %d failed", i)
+		}
+		if test.directive != "" {
+			require.Equal(t, test.directive, e.DirectivePos.String(), "Test %d failed", i)
+		}
+		e.NodePos = test.expected.NodePos
+		e.DirectivePos = test.expected.DirectivePos
+		require.Equal(t, test.expected, *e, "Test %d failed", i)
+	}
+
+	_, err := Explain(src, 100, Options{Mode: AllMode})
+	require.EqualError(t, err, "Line 100 is out of range (1-19)")
+}
+
+func TestExplainFragment(t *testing.T) {
+	e, err := Explain("func a() {\n\n\tb()\n}\n", 2, Options{Mode: AllMode, Fragment: true})
+	require.NoError(t, err)
+	require.True(t, e.Removed)
+	require.Equal(t, 1, e.NodePos.Line)
+	require.Equal(t, []string{"*ast.BlockStmt", "*ast.FuncDecl"}, e.Path)
+
+	e, err = Explain("a()\n\nif b {\n\n\tc()\n}\n", 4, Options{Mode: AllMode, Fragment: true})
+	require.NoError(t, err)
+	require.True(t, e.Removed)
+	require.Equal(t, IfMode, e.Mode)
+	require.Equal(t, 3, e.NodePos.Line)
+	require.Equal(t, []string{"*ast.BlockStmt", "*ast.IfStmt"}, e.Path)
+
+	e, err = Explain("package a\n\nfunc a() {\n\n\tb(\n}\n", 4, Options{Mode: AllMode, Tolerant: true})
+	require.NoError(t, err)
+	require.True(t, e.Skipped)
+	require.False(t, e.Removed)
+}
@@ -0,0 +1,120 @@
+package goremovelines
+
+import (
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// fragment is the wrapper that turns a fragment into a complete file.
+// The prefix ends with a line break, so the fragment starts at the beginning of the second line.
+type fragment struct {
+	prefix string
+	suffix string
+	// stmts is set if the fragment is a statement list, it is wrapped in a function that is not cleaned itself.
+	stmts bool
+}
+
+var (
+	declFragment = fragment{prefix: "package p\n"}
+	stmtFragment = fragment{prefix: "package p; func _() {\n", suffix: "\n}\n", stmts: true}
+)
+
+// detectFragment returns the wrapper for src the same way go/format.Source does, nil if src is a complete file.
+func detectFragment(src, filename string) *fragment {
+	_, err := parser.ParseFile(token.NewFileSet(), filename, src, parser.PackageClauseOnly)
+	if err == nil || !strings.Contains(err.Error(), "expected 'package'") {
+		return nil
+	}
+	_, err = parser.ParseFile(token.NewFileSet(), filename, declFragment.prefix+src, 0)
+	if err == nil || !strings.Contains(err.Error(), "expected declaration") {
+		return &declFragment
+	}
+	return &stmtFragment
+}
+
+// cleanFragment cleans src that is either a complete file or a fragment, the wrapper of a fragment is
+// removed again and all positions are relative to the fragment.
+func cleanFragment(src *string, opts *Options) (*Result, error) {
+	f := detectFragment(*src, opts.Filename)
+	if f == nil {
+		return cleanFile(src, opts, false)
+	}
+
+	wrapped := f.prefix + *src + f.suffix
+	result, err := cleanFile(&wrapped, opts, f.stmts)
+	if err != nil {
+		var parseErr *ParseError
+		if errors.As(err, &parseErr) {
+			for _, e := range parseErr.Errors {
+				f.convertError(e, *src)
+			}
+		}
+		var verifyErr *VerifyError
+		if errors.As(err, &verifyErr) {
+			verifyErr.Pos = f.clamp(f.position(verifyErr.Pos), *src)
+		}
+		return nil, err
+	}
+	for i := range result.Skipped {
+		d := &result.Skipped[i]
+		d.Pos = f.position(d.Pos)
+		d.End = f.clamp(f.position(d.End), *src)
+		for _, e := range d.Errors {
+			f.convertError(e, *src)
+		}
+	}
+	for i := range result.Removed {
+		// the prefix is a single line that is never removed
+		result.Removed[i].Line--
+	}
+	*src = wrapped[len(f.prefix) : len(wrapped)-len(f.suffix)]
+	return result, nil
+}
+
+// position converts a position in the wrapped source to a position in the fragment.
+func (f *fragment) position(pos token.Position) token.Position {
+	if !pos.IsValid() {
+		return pos
+	}
+	if pos.Line > 1 {
+		pos.Line--
+	} else {
+		pos.Column = 1
+	}
+	pos.Offset -= len(f.prefix)
+	if pos.Offset < 0 {
+		pos.Offset = 0
+	}
+	return pos
+}
+
+// clamp moves a position of the fragment src that is inside the suffix of the wrapper to the end of src,
+// the end is reported like go/parser reports the end of a file: on the last line, after its last character.
+func (f *fragment) clamp(pos token.Position, src string) token.Position {
+	if !pos.IsValid() || pos.Offset < len(src) {
+		return pos
+	}
+	lines := strings.TrimSuffix(src, "\n")
+	pos.Line = strings.Count(lines, "\n") + 1
+	pos.Column = len(src) - (strings.LastIndex(lines, "\n") + 1) + 1
+	pos.Offset = len(src)
+	return pos
+}
+
+// convertError converts the position of a syntax error in the wrapped source to a position in the fragment src.
+// Errors in the suffix of the wrapper are reported at the end of src, as if the file ended there.
+func (f *fragment) convertError(e *scanner.Error, src string) {
+	e.Pos = f.position(e.Pos)
+	if f.suffix == "" || !e.Pos.IsValid() || e.Pos.Offset < len(src) {
+		return
+	}
+	e.Pos = f.clamp(e.Pos, src)
+	// the token that was found is part of the wrapper
+	if i := strings.LastIndex(e.Msg, ", found "); i >= 0 {
+		e.Msg = e.Msg[:i] + ", found 'EOF'"
+	}
+}
@@ -0,0 +1,74 @@
+package goremovelines
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanFragment(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{
+			"package a\n\nfunc a() {\n\n\treturn\n}\n",
+			"package a\n\nfunc a() {\n\treturn\n}\n",
+		},
+		{
+			"func a() {\n\n\treturn\n}\n\ntype T struct {\n\n\tA int\n}",
+			"func a() {\n\treturn\n}\n\ntype T struct {\n\tA int\n}",
+		},
+		{
+			"\tif x {\n\n\t\ty()\n\t}\n\n\tfor {\n\n\t\tbreak\n\n\t}\n",
+			"\tif x {\n\t\ty()\n\t}\n\n\tfor {\n\t\tbreak\n\t}\n",
+		},
+		{
+			"\n\nx := func() {\n\n\ty()\n}\n\n",
+			"\n\nx := func() {\n\ty()\n}\n\n",
+		},
+		{
+			"// comment\nvar x = 1\n",
+			"// comment\nvar x = 1\n",
+		},
+		{
+			"//goremovelines:ignore\nif x {\n\n\ty()\n}\n",
+			"//goremovelines:ignore\nif x {\n\n\ty()\n}\n",
+		},
+	}
+
+	for i, test := range tests {
+		result, err := Clean(test.src, Options{Mode: AllMode, Fragment: true})
+		require.NoError(t, err, "Test %d failed", i)
+		require.Equal(t, test.expected, result.Source, "Test %d failed", i)
+	}
+}
+
+func TestCleanFragmentErrors(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{"if {\n}\n", "a.go:1:4: missing condition in if statement"},
+		// errors in the wrapper are reported at the end of the fragment
+		{"x := 1\n\ny(\n", "a.go:3:4: expected operand, found 'EOF'"},
+		{"x := 1\n\ny(", "a.go:3:3: expected operand, found 'EOF'"},
+		{"if x {\n\ty()\n", "a.go:2:6: expected '}', found 'EOF'"},
+		{"func a() {\n\n\tb(\n}\n\nfunc c() {\n}\n", "a.go:4:1: expected operand, found '}' (and 1 more errors)"},
+	}
+
+	for i, test := range tests {
+		_, err := Clean(test.src, Options{Mode: AllMode, Fragment: true, Filename: "a.go"})
+		var parseErr *ParseError
+		require.True(t, errors.As(err, &parseErr), "Test %d failed", i)
+		require.Equal(t, test.expected, err.Error(), "Test %d failed", i)
+	}
+
+	result, err := Clean("func a() {\n\n\tb(\n}\n\nfunc c() {\n\n}\n", Options{Mode: AllMode, Fragment: true, Tolerant: true})
+	require.NoError(t, err)
+	require.Equal(t, "func a() {\n\n\tb(\n}\n\nfunc c() {\n}\n", result.Source)
+	require.Len(t, result.Skipped, 1)
+	require.Equal(t, 1, result.Skipped[0].Pos.Line)
+	require.Equal(t, 4, result.Skipped[0].Errors[0].Pos.Line)
+}
@@ -0,0 +1,37 @@
+package goremovelines
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// IsGenerated reports whether the source has been marked as generated by a tool.
+// It uses the same rule as ast.IsGenerated: a line comment of the form
+// `// Code generated ... DO NOT EDIT.` has to appear before the package clause.
+func IsGenerated(src string) bool {
+	set := token.NewFileSet()
+	astFile, err := parser.ParseFile(set, "", src, parser.PackageClauseOnly|parser.ParseComments)
+	if err != nil {
+		return false
+	}
+
+	const prefix = "// Code generated "
+	const suffix = " DO NOT EDIT."
+	for _, group := range astFile.Comments {
+		for _, comment := range group.List {
+			if comment.Pos() > astFile.Package {
+				return false
+			}
+			if !strings.Contains(comment.Text, prefix) {
+				continue
+			}
+			for _, line := range strings.Split(comment.Text, "\n") {
+				if rest, ok := strings.CutPrefix(line, prefix); ok && strings.HasSuffix(rest, suffix) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package goremovelines
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsGenerated(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage foo\n", true},
+		{"// Copyright\n\n// Code generated by mockgen. DO NOT EDIT.\npackage foo\n", true},
+		{"/*\n// Code generated by stringer. DO NOT EDIT.\n*/\npackage foo\n", true},
+
+		// marker after the package clause
+		{"package foo\n\n// Code generated by stringer. DO NOT EDIT.\n", false},
+		// missing dot
+		{"// Code generated by stringer. DO NOT EDIT\npackage foo\n", false},
+		// the prefix and the suffix do not share the space
+		{"// Code generated DO NOT EDIT.\npackage foo\n", false},
+		// not at the start of the line
+		{"// This is not Code generated by stringer. DO NOT EDIT.\npackage foo\n", false},
+		{"package foo\n", false},
+
+		// invalid
+		{"", false},
+	}
+
+	for i, test := range tests {
+		require.Equal(t, test.expected, IsGenerated(test.input), "Test %d failed", i)
+	}
+}
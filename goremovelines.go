@@ -2,12 +2,15 @@ package goremovelines
 
 import (
 	"bytes"
+	"fmt"
 	"go/ast"
+	"go/format"
 	"go/parser"
 	"go/token"
 	"io"
 	"log"
 	"os"
+	"sort"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -15,53 +18,40 @@ import (
 	"github.com/pkg/errors"
 )
 
-// Mode is a bitmask that defines which lines should be removed.
-type Mode int
-
-const (
-	// FuncMode should be set to remove empty lines in functions.
-	FuncMode = 1 << iota
-	// StructMode should be set to remove empty lines in structs.
-	StructMode = 1 << iota
-	// IfMode should be set to remove empty lines in if blocks.
-	IfMode = 1 << iota
-	// SwitchMode should be set to remove empty lines in functions.
-	SwitchMode = 1 << iota
-	// CaseMode should be set to remove empty lines in case blocks.
-	CaseMode = 1 << iota
-	// ForMode should be set to remove empty lines in for blocks.
-	ForMode = 1 << iota
-	// InterfaceMode should be set to remove empty lines in interface blocks.
-	InterfaceMode = 1 << iota
-	// BlockMode should be set to remove empty lines in blocks.
-	BlockMode = 1 << iota
-	// AllMode includes all modes.
-	AllMode = FuncMode | StructMode | IfMode | SwitchMode | CaseMode | ForMode | InterfaceMode | BlockMode
-)
-
 // Debug enables/disables debug output.
 var Debug = false
 
+// MaxIterations limits the number of passes over a source, every pass removes at most one line,
+// so a source with n lines to remove takes n+1 passes.
+// If it is 0, the number of lines of the source is used.
+var MaxIterations = 0
+
 // CleanFilePath cleans a file with the specific mode, it writes the cleaned output to `out`.
 func CleanFilePath(path string, out io.Writer, mode Mode) error {
 	f, err := os.Open(path)
 	if err != nil {
-		return errors.Errorf("Unable to open `%s'", path)
+		return errors.Wrapf(err, "Unable to open `%s'", path)
 	}
 	var b bytes.Buffer
 	_, err = io.Copy(&b, f)
 	if err != nil {
-		return err
+		f.Close()
+		return errors.Wrapf(err, "Unable to read `%s'", path)
 	}
 	if err := f.Close(); err != nil {
+		return errors.Wrapf(err, "Unable to close `%s'", path)
+	}
+	src := b.String()
+	if _, err := clean(&src, &Options{Mode: mode, Filename: path}); err != nil {
 		return err
 	}
-	return CleanFile(b.String(), out, mode)
+	_, err = io.WriteString(out, src)
+	return err
 }
 
 // CleanFile cleans a source code with the specific mode, it writes the cleaned output to `out`.
 func CleanFile(src string, out io.Writer, mode Mode) error {
-	err := clean(&src, mode)
+	_, err := clean(&src, &Options{Mode: mode})
 	if err != nil {
 		return err
 	}
@@ -69,7 +59,9 @@ func CleanFile(src string, out io.Writer, mode Mode) error {
 	return err
 }
 
-func clean(src *string, mode Mode) error {
+// clean cleans src until nothing is left to clean, it returns the declarations that were skipped in tolerant mode
+// and the removed lines, the source of the result is not set.
+func clean(src *string, opts *Options) (*Result, error) {
 	if Debug {
 		lines := strings.Split(*src, "\n")
 		for i, line := range lines {
@@ -77,24 +69,189 @@ func clean(src *string, mode Mode) error {
 		}
 		log.Printf("Cleaning \n%s\n", strings.Join(lines, "\n"))
 	}
-cleanAgain:
-	set := token.NewFileSet()
-	astFile, err := parser.ParseFile(set, "", *src, parser.ParseComments)
+	if !opts.Gofmt {
+		return cleanSource(src, opts)
+	}
+
+	result, err := cleanSource(src, opts)
+	if err != nil || len(result.Skipped) > 0 {
+		// sources with syntax errors can not be formatted
+		return result, err
+	}
+	if err := formatSource(src, opts); err != nil {
+		return nil, err
+	}
+	// the result has to be stable, otherwise the next run would change it again
+	again := *src
+	if _, err := cleanSource(&again, opts); err != nil {
+		return nil, err
+	}
+	if err := formatSource(&again, opts); err != nil {
+		return nil, err
+	}
+	if again != *src {
+		if opts.Filename != "" {
+			return nil, errors.Errorf("Result of `%s' is not stable under a second pass", opts.Filename)
+		}
+		return nil, errors.Errorf("Result is not stable under a second pass")
+	}
+	return result, nil
+}
+
+// cleanSource cleans src as a file or (if enabled) as a fragment.
+func cleanSource(src *string, opts *Options) (*Result, error) {
+	if opts.Fragment {
+		return cleanFragment(src, opts)
+	}
+	return cleanFile(src, opts, false)
+}
+
+// formatter formats the result if Options.Gofmt is set.
+var formatter = format.Source
+
+// formatSource formats src with go/format.
+func formatSource(src *string, opts *Options) error {
+	buf, err := formatter([]byte(*src))
 	if err != nil {
-		return errors.Errorf("Failed to parse `%s': %v", *src, err)
+		if opts.Filename != "" {
+			return errors.Wrapf(err, "Unable to format `%s'", opts.Filename)
+		}
+		return errors.Wrap(err, "Unable to format")
+	}
+	*src = string(buf)
+	return nil
+}
+
+// cleanFile cleans the complete file src until nothing is left to clean,
+// if stmts is set the first function wraps a statement fragment and is not cleaned itself.
+func cleanFile(src *string, opts *Options, stmts bool) (*Result, error) {
+	original := *src
+	result, err := cleanLoop(src, opts, stmts)
+	if err != nil || !opts.Verify {
+		return result, err
+	}
+	if err := verify(original, *src, opts); err != nil {
+		return nil, err
 	}
+	return result, nil
+}
 
-	for i := 0; i < len(astFile.Decls); i++ {
-		mod, err := cleanNode(src, astFile.Decls[i], mode)
+// cleanLoop runs cleanOnce until nothing is left to clean, the lines of the removals are lines of the original src.
+func cleanLoop(src *string, opts *Options, stmts bool) (*Result, error) {
+	limit := MaxIterations
+	if limit <= 0 {
+		limit = strings.Count(*src, "\n") + 1
+	}
+	result := &Result{}
+	// the sorted lines of the original src that were removed
+	var removed []int
+	for i := 0; ; i++ {
+		if err := opts.canceled(); err != nil {
+			return nil, err
+		}
+		if i >= limit {
+			if opts.Filename != "" {
+				return nil, errors.Errorf("Giving up on `%s' after %d iterations", opts.Filename, limit)
+			}
+			return nil, errors.Errorf("Giving up after %d iterations", limit)
+		}
+		removal, skipped, err := cleanOnce(src, opts, stmts)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		if mod {
-			goto cleanAgain
+		if removal == nil {
+			result.Skipped = skipped
+			sort.Slice(result.Removed, func(i, j int) bool {
+				return result.Removed[i].Line < result.Removed[j].Line
+			})
+			return result, nil
+		}
+		for _, line := range removed {
+			if line > removal.Line {
+				break
+			}
+			removal.Line++
+		}
+		j := sort.SearchInts(removed, removal.Line)
+		removed = append(removed[:j], append([]int{removal.Line}, removed[j:]...)...)
+		result.Removed = append(result.Removed, *removal)
+	}
+}
+
+// cleanOnce parses src and removes the first blank line that should be removed.
+// It returns the removal (nil if src was not modified) and the declarations that were skipped in tolerant mode.
+func cleanOnce(src *string, opts *Options, stmts bool) (*Removal, []SkippedDecl, error) {
+	var set *token.FileSet
+	var astFile *ast.File
+	var skipped []SkippedDecl
+	var err error
+	if opts.Tolerant {
+		set, astFile, skipped, err = parseTolerant(*src, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		set = token.NewFileSet()
+		astFile, err = parser.ParseFile(set, opts.Filename, *src, parser.ParseComments)
+		if err != nil {
+			return nil, nil, newParseError(opts.Filename, err)
 		}
 	}
 
-	return nil
+	var d *directives
+	if !opts.ignoreDirectives {
+		d, err = parseDirectives(set, astFile)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	c := cleaner{
+		src:        src,
+		set:        set,
+		directives: d,
+		stmts:      stmts,
+	}
+	mod, err := c.cleanDecls(astFile, opts.Mode)
+	if err != nil {
+		return nil, nil, err
+	}
+	if mod {
+		return c.removed, nil, nil
+	}
+	return nil, skipped, nil
+}
+
+// cleanDecls cleans the declarations of the file until the first modification, panics are returned as errors.
+func (c *cleaner) cleanDecls(astFile *ast.File, mode Mode) (mod bool, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			mod = false
+			err = errors.Errorf("Panic while cleaning %s: %v", c.set.Position(c.pos), r)
+		}
+	}()
+
+	nodes := make([]interface{}, 0, len(astFile.Decls))
+	for i, decl := range astFile.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && c.stmts && i == 0 && fn.Body != nil {
+			// the function that wraps a statement fragment is not cleaned itself
+			for _, stmt := range fn.Body.List {
+				nodes = append(nodes, stmt)
+			}
+			continue
+		}
+		nodes = append(nodes, decl)
+	}
+
+	for i := 0; i < len(nodes); i++ {
+		mod, err := c.cleanNode(nodes[i], mode)
+		if err != nil {
+			return false, err
+		}
+		if mod {
+			return true, nil
+		}
+	}
+	return false, nil
 }
 
 func findRealStartOfBody(src string, start, end int) int {
@@ -147,17 +304,22 @@ func cleanSrc(src *string, start, end token.Pos) (bool, error) {
 	startOfBody := int(start)
 	endOfBody := int(end)
 
-	for (*src)[startOfBody] != '{' {
-		startOfBody--
-	}
-
 	// fix out of bounds
+	if size := len(*src); startOfBody >= size {
+		startOfBody = size - 1
+	}
 	for size := len(*src); endOfBody >= size; endOfBody-- {
 	}
 
-	for (*src)[endOfBody] != '}' {
+	for startOfBody >= 0 && (*src)[startOfBody] != '{' {
+		startOfBody--
+	}
+	for endOfBody > startOfBody && (*src)[endOfBody] != '}' {
 		endOfBody--
 	}
+	if startOfBody < 0 || endOfBody <= startOfBody {
+		return false, nil
+	}
 
 	if Debug {
 		lines := strings.Split((*src)[startOfBody:endOfBody], "\n")
@@ -208,12 +370,23 @@ func cleanCase(src *string, start, end token.Pos, isLastCase bool) bool {
 	startOfBody := int(start)
 	endOfBody := int(end)
 
-	for (*src)[startOfBody] != '\n' {
+	// fix out of bounds
+	if size := len(*src); startOfBody >= size {
+		startOfBody = size - 1
+	}
+	if size := len(*src); endOfBody >= size {
+		endOfBody = size - 1
+	}
+
+	for startOfBody >= 0 && (*src)[startOfBody] != '\n' {
 		startOfBody--
 	}
-	for (*src)[endOfBody] != '\n' {
+	for endOfBody > startOfBody && (*src)[endOfBody] != '\n' {
 		endOfBody--
 	}
+	if startOfBody < 0 || endOfBody <= startOfBody {
+		return false
+	}
 
 	findRealStartOfBodyCase := func(src string, start, end int) int {
 		if start < 0 || end < 0 || end <= start || end >= len(src) || src[start:end] == "" {
@@ -303,11 +476,62 @@ func cleanCase(src *string, start, end token.Pos, isLastCase bool) bool {
 	return false
 }
 
-func cleanNode(src *string, node interface{}, mode Mode) (bool, error) {
+// cleaner holds the state of one cleaning pass.
+type cleaner struct {
+	src        *string
+	set        *token.FileSet
+	directives *directives
+	// stmts is set if the first declaration is the function that wraps a statement fragment.
+	stmts bool
+	// pos is the position of the node that is cleaned, it is used for error messages.
+	pos token.Pos
+	// removed is the line that was removed by the pass.
+	removed *Removal
+}
+
+// cleanSrc runs cleanSrc for the body of node and records the removal for the mode.
+func (c *cleaner) cleanSrc(node ast.Node, mode Mode, start, end token.Pos) (bool, error) {
+	before := *c.src
+	mod, err := cleanSrc(c.src, start, end)
+	if mod {
+		c.record(node, mode, before)
+	}
+	return mod, err
+}
+
+// cleanCase runs cleanCase for the clause and records the removal.
+func (c *cleaner) cleanCase(clause *ast.CaseClause, isLastCase bool) bool {
+	before := *c.src
+	mod := cleanCase(c.src, clause.Colon, clause.End(), isLastCase)
+	if mod {
+		c.record(clause, CaseMode, before)
+	}
+	return mod
+}
+
+// record records the line that was removed from before, it is the line of the first difference to the source.
+func (c *cleaner) record(node ast.Node, mode Mode, before string) {
+	i := 0
+	for i < len(before) && i < len(*c.src) && before[i] == (*c.src)[i] {
+		i++
+	}
+	c.removed = &Removal{
+		Line: strings.Count(before[:i], "\n") + 1,
+		Mode: mode,
+		Node: fmt.Sprintf("%T", node),
+	}
+}
+
+func (c *cleaner) cleanNode(node interface{}, mode Mode) (bool, error) {
+	if n, ok := node.(ast.Node); ok {
+		c.pos = n.Pos()
+		mode = c.directives.apply(c.set, n, mode)
+	}
+
 	switch v := node.(type) {
 	case *ast.GenDecl:
 		for i := 0; i < len(v.Specs); i++ {
-			mod, err := cleanNode(src, v.Specs[i], mode)
+			mod, err := c.cleanNode(v.Specs[i], mode)
 			if err != nil {
 				return false, err
 			}
@@ -316,11 +540,11 @@ func cleanNode(src *string, node interface{}, mode Mode) (bool, error) {
 			}
 		}
 	case *ast.DeclStmt:
-		return cleanNode(src, v.Decl, mode)
+		return c.cleanNode(v.Decl, mode)
 	case *ast.ExprStmt:
-		return cleanNode(src, v.X, mode)
+		return c.cleanNode(v.X, mode)
 	case *ast.CallExpr:
-		mod, err := cleanNode(src, v.Fun, mode)
+		mod, err := c.cleanNode(v.Fun, mode)
 		if err != nil {
 			return false, err
 		}
@@ -328,7 +552,7 @@ func cleanNode(src *string, node interface{}, mode Mode) (bool, error) {
 			return true, nil
 		}
 		for i := 0; i < len(v.Args); i++ {
-			mod, err := cleanNode(src, v.Args[i], mode)
+			mod, err := c.cleanNode(v.Args[i], mode)
 			if err != nil {
 				return false, err
 			}
@@ -338,7 +562,7 @@ func cleanNode(src *string, node interface{}, mode Mode) (bool, error) {
 		}
 	case *ast.AssignStmt:
 		for i := 0; i < len(v.Lhs); i++ {
-			mod, err := cleanNode(src, v.Lhs[i], mode)
+			mod, err := c.cleanNode(v.Lhs[i], mode)
 			if err != nil {
 				return false, err
 			}
@@ -347,7 +571,7 @@ func cleanNode(src *string, node interface{}, mode Mode) (bool, error) {
 			}
 		}
 		for i := 0; i < len(v.Rhs); i++ {
-			mod, err := cleanNode(src, v.Rhs[i], mode)
+			mod, err := c.cleanNode(v.Rhs[i], mode)
 			if err != nil {
 				return false, err
 			}
@@ -356,7 +580,7 @@ func cleanNode(src *string, node interface{}, mode Mode) (bool, error) {
 			}
 		}
 	case *ast.ValueSpec:
-		mod, err := cleanNode(src, v.Type, mode)
+		mod, err := c.cleanNode(v.Type, mode)
 		if err != nil {
 			return false, err
 		}
@@ -364,7 +588,7 @@ func cleanNode(src *string, node interface{}, mode Mode) (bool, error) {
 			return true, nil
 		}
 		for i := 0; i < len(v.Values); i++ {
-			mod, err := cleanNode(src, v.Values[i], mode)
+			mod, err := c.cleanNode(v.Values[i], mode)
 			if err != nil {
 				return false, err
 			}
@@ -373,13 +597,13 @@ func cleanNode(src *string, node interface{}, mode Mode) (bool, error) {
 			}
 		}
 	case *ast.SelectorExpr:
-		return cleanNode(src, v.X, mode)
+		return c.cleanNode(v.X, mode)
 	case *ast.BasicLit:
 		if v.Kind == token.FUNC {
-			return cleanSrc(src, v.Pos(), v.End())
+			return c.cleanSrc(v, FuncMode, v.Pos(), v.End())
 		}
 	case *ast.TypeSpec:
-		return cleanNode(src, v.Type, mode)
+		return c.cleanNode(v.Type, mode)
 	// funcs
 	case *ast.FuncDecl:
 		if v.Body == nil {
@@ -387,7 +611,7 @@ func cleanNode(src *string, node interface{}, mode Mode) (bool, error) {
 		}
 
 		if mode&FuncMode == FuncMode {
-			mod, err := cleanSrc(src, v.Body.Lbrace, v.Body.Rbrace)
+			mod, err := c.cleanSrc(v, FuncMode, v.Body.Lbrace, v.Body.Rbrace)
 			if err != nil {
 				return false, err
 			}
@@ -397,7 +621,7 @@ func cleanNode(src *string, node interface{}, mode Mode) (bool, error) {
 		}
 
 		for i := 0; i < len(v.Body.List); i++ {
-			mod, err := cleanNode(src, v.Body.List[i], mode)
+			mod, err := c.cleanNode(v.Body.List[i], mode)
 			if err != nil {
 				return false, err
 			}
@@ -407,7 +631,7 @@ func cleanNode(src *string, node interface{}, mode Mode) (bool, error) {
 		}
 	case *ast.FuncLit:
 		if mode&FuncMode == FuncMode {
-			mod, err := cleanSrc(src, v.Body.Lbrace, v.Body.Rbrace)
+			mod, err := c.cleanSrc(v, FuncMode, v.Body.Lbrace, v.Body.Rbrace)
 			if err != nil {
 				return false, err
 			}
@@ -417,7 +641,7 @@ func cleanNode(src *string, node interface{}, mode Mode) (bool, error) {
 		}
 
 		for i := 0; i < len(v.Body.List); i++ {
-			mod, err := cleanNode(src, v.Body.List[i], mode)
+			mod, err := c.cleanNode(v.Body.List[i], mode)
 			if err != nil {
 				return false, err
 			}
@@ -428,10 +652,10 @@ func cleanNode(src *string, node interface{}, mode Mode) (bool, error) {
 	// structs
 	case *ast.StructType:
 		if mode&StructMode == StructMode {
-			return cleanSrc(src, v.Fields.Opening, v.Fields.Closing)
+			return c.cleanSrc(v, StructMode, v.Fields.Opening, v.Fields.Closing)
 		}
 	case *ast.CompositeLit:
-		mod, err := cleanNode(src, v.Type, mode)
+		mod, err := c.cleanNode(v.Type, mode)
 		if err != nil {
 			return false, err
 		}
@@ -439,7 +663,7 @@ func cleanNode(src *string, node interface{}, mode Mode) (bool, error) {
 			return true, nil
 		}
 		for i := 0; i < len(v.Elts); i++ {
-			mod, err := cleanNode(src, v.Elts[i], mode)
+			mod, err := c.cleanNode(v.Elts[i], mode)
 			if err != nil {
 				return false, err
 			}
@@ -451,22 +675,22 @@ func cleanNode(src *string, node interface{}, mode Mode) (bool, error) {
 		// if this was a struct, clean the list also
 		if mode&StructMode == StructMode {
 			if _, ok := v.Type.(*ast.StructType); ok {
-				return cleanSrc(src, v.Lbrace, v.Rbrace)
+				return c.cleanSrc(v, StructMode, v.Lbrace, v.Rbrace)
 			}
 		}
 	case *ast.KeyValueExpr:
-		mod, err := cleanNode(src, v.Key, mode)
+		mod, err := c.cleanNode(v.Key, mode)
 		if err != nil {
 			return false, err
 		}
 		if mod {
 			return true, nil
 		}
-		return cleanNode(src, v.Value, mode)
+		return c.cleanNode(v.Value, mode)
 	// if
 	case *ast.IfStmt:
 		if mode&IfMode == IfMode {
-			mod, err := cleanSrc(src, v.Body.Lbrace, v.Body.Rbrace)
+			mod, err := c.cleanSrc(v, IfMode, v.Body.Lbrace, v.Body.Rbrace)
 			if err != nil {
 				return false, err
 			}
@@ -475,7 +699,7 @@ func cleanNode(src *string, node interface{}, mode Mode) (bool, error) {
 			}
 
 			if elseBlock, ok := v.Else.(*ast.BlockStmt); ok {
-				mod, err := cleanSrc(src, elseBlock.Lbrace, elseBlock.Rbrace)
+				mod, err := c.cleanSrc(v, IfMode, elseBlock.Lbrace, elseBlock.Rbrace)
 				if err != nil {
 					return false, err
 				}
@@ -485,7 +709,7 @@ func cleanNode(src *string, node interface{}, mode Mode) (bool, error) {
 			}
 		}
 
-		mod, err := cleanNode(src, v.Else, mode)
+		mod, err := c.cleanNode(v.Else, mode)
 		if err != nil {
 			return false, err
 		}
@@ -494,7 +718,7 @@ func cleanNode(src *string, node interface{}, mode Mode) (bool, error) {
 		}
 
 		for i := 0; i < len(v.Body.List); i++ {
-			mod, err := cleanNode(src, v.Body.List[i], mode)
+			mod, err := c.cleanNode(v.Body.List[i], mode)
 			if err != nil {
 				return false, err
 			}
@@ -505,7 +729,7 @@ func cleanNode(src *string, node interface{}, mode Mode) (bool, error) {
 	// switch
 	case *ast.SwitchStmt:
 		if mode&SwitchMode == SwitchMode {
-			mod, err := cleanSrc(src, v.Body.Lbrace, v.Body.Rbrace)
+			mod, err := c.cleanSrc(v, SwitchMode, v.Body.Lbrace, v.Body.Rbrace)
 			if err != nil {
 				return false, err
 			}
@@ -516,14 +740,14 @@ func cleanNode(src *string, node interface{}, mode Mode) (bool, error) {
 		lastIndex := len(v.Body.List) - 1
 		for i := 0; i < len(v.Body.List); i++ {
 			if caseClause, ok := v.Body.List[i].(*ast.CaseClause); ok {
-				if mode&CaseMode == CaseMode {
-					mod := cleanCase(src, caseClause.Colon, caseClause.End(), i == lastIndex)
+				if c.directives.apply(c.set, caseClause, mode)&CaseMode == CaseMode {
+					mod := c.cleanCase(caseClause, i == lastIndex)
 					if mod {
 						return true, nil
 					}
 				}
 				for j := 0; j < len(caseClause.Body); j++ {
-					mod, err := cleanNode(src, caseClause.Body[j], mode)
+					mod, err := c.cleanNode(caseClause.Body[j], mode)
 					if err != nil {
 						return false, err
 					}
@@ -532,7 +756,7 @@ func cleanNode(src *string, node interface{}, mode Mode) (bool, error) {
 					}
 				}
 			}
-			mod, err := cleanNode(src, v.Body.List[i], mode)
+			mod, err := c.cleanNode(v.Body.List[i], mode)
 			if err != nil {
 				return false, err
 			}
@@ -543,7 +767,7 @@ func cleanNode(src *string, node interface{}, mode Mode) (bool, error) {
 	// for
 	case *ast.ForStmt:
 		if mode&ForMode == ForMode {
-			mod, err := cleanSrc(src, v.Body.Lbrace, v.Body.Rbrace)
+			mod, err := c.cleanSrc(v, ForMode, v.Body.Lbrace, v.Body.Rbrace)
 			if err != nil {
 				return false, err
 			}
@@ -552,7 +776,7 @@ func cleanNode(src *string, node interface{}, mode Mode) (bool, error) {
 			}
 		}
 		for i := 0; i < len(v.Body.List); i++ {
-			mod, err := cleanNode(src, v.Body.List[i], mode)
+			mod, err := c.cleanNode(v.Body.List[i], mode)
 			if err != nil {
 				return false, err
 			}
@@ -563,7 +787,7 @@ func cleanNode(src *string, node interface{}, mode Mode) (bool, error) {
 	// for range
 	case *ast.RangeStmt:
 		if mode&ForMode == ForMode {
-			mod, err := cleanSrc(src, v.Body.Lbrace, v.Body.Rbrace)
+			mod, err := c.cleanSrc(v, ForMode, v.Body.Lbrace, v.Body.Rbrace)
 			if err != nil {
 				return false, err
 			}
@@ -572,7 +796,7 @@ func cleanNode(src *string, node interface{}, mode Mode) (bool, error) {
 			}
 		}
 		for i := 0; i < len(v.Body.List); i++ {
-			mod, err := cleanNode(src, v.Body.List[i], mode)
+			mod, err := c.cleanNode(v.Body.List[i], mode)
 			if err != nil {
 				return false, err
 			}
@@ -583,12 +807,12 @@ func cleanNode(src *string, node interface{}, mode Mode) (bool, error) {
 	// interface
 	case *ast.InterfaceType:
 		if mode&InterfaceMode == InterfaceMode {
-			return cleanSrc(src, v.Methods.Opening, v.Methods.Closing)
+			return c.cleanSrc(v, InterfaceMode, v.Methods.Opening, v.Methods.Closing)
 		}
 	// block
 	case *ast.BlockStmt:
 		if mode&BlockMode == BlockMode {
-			mod, err := cleanSrc(src, v.Lbrace, v.Rbrace)
+			mod, err := c.cleanSrc(v, BlockMode, v.Lbrace, v.Rbrace)
 			if err != nil {
 				return false, err
 			}
@@ -597,7 +821,7 @@ func cleanNode(src *string, node interface{}, mode Mode) (bool, error) {
 			}
 		}
 		for i := 0; i < len(v.List); i++ {
-			mod, err := cleanNode(src, v.List[i], mode)
+			mod, err := c.cleanNode(v.List[i], mode)
 			if err != nil {
 				return false, err
 			}
@@ -2,6 +2,9 @@ package goremovelines
 
 import (
 	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"io"
 	"os"
 	"path/filepath"
@@ -65,6 +68,10 @@ func runTest(t *testing.T, test string) {
 	var cleanedBuffer bytes.Buffer
 	require.NoError(t, CleanFilePath(inputFile, &cleanedBuffer, mode), "Clean for `%s' failed!", test)
 	require.Equal(t, expectedBuffer.String(), cleanedBuffer.String(), "Test `%s' failed!", test)
+
+	result, err := Clean(inputBuffer.String(), Options{Mode: mode, Filename: inputFile, Verify: true})
+	require.NoError(t, err, "Verify for `%s' failed!", test)
+	require.Equal(t, expectedBuffer.String(), result.Source, "Test `%s' failed!", test)
 }
 
 func TestAllTests(t *testing.T) {
@@ -158,3 +165,104 @@ func TestRealBody(t *testing.T) {
 		require.Equal(t, test.expected, realBody, "Test %d failed", i)
 	}
 }
+
+func TestMaxIterations(t *testing.T) {
+	defer func(maxIterations int) {
+		MaxIterations = maxIterations
+	}(MaxIterations)
+
+	src := "package main\n\nfunc main() {\n\n\n\tprintln()\n}\n"
+
+	// two lines are removed in two passes, the third pass finds nothing to remove
+	tests := []struct {
+		maxIterations int
+		err           string
+	}{
+		{1, "Giving up after 1 iterations"},
+		{2, "Giving up after 2 iterations"},
+		{3, ""},
+	}
+	var out bytes.Buffer
+	for i, test := range tests {
+		MaxIterations = test.maxIterations
+		out.Reset()
+		err := CleanFile(src, &out, AllMode)
+		if test.err != "" {
+			require.EqualError(t, err, test.err, "Test %d failed", i)
+			continue
+		}
+		require.NoError(t, err, "Test %d failed", i)
+	}
+
+	// a clean source takes a single pass
+	MaxIterations = 1
+	out.Reset()
+	require.NoError(t, CleanFile("package main\n", &out, AllMode))
+
+	MaxIterations = 0
+	out.Reset()
+	require.NoError(t, CleanFile(src, &out, AllMode))
+	require.Equal(t, "package main\n\nfunc main() {\n\tprintln()\n}\n", out.String())
+}
+
+func TestPanicRecovery(t *testing.T) {
+	set := token.NewFileSet()
+	src := "package main\n\nfunc main() {\n\tprintln()\n}\n"
+	astFile, err := parser.ParseFile(set, "main.go", src, parser.ParseComments)
+	require.NoError(t, err)
+
+	// corrupt the ast so that cleaning panics
+	astFile.Decls[0].(*ast.FuncDecl).Body.List = []ast.Stmt{(*ast.IfStmt)(nil)}
+
+	c := cleaner{src: &src, set: set}
+	_, err = c.cleanDecls(astFile, AllMode)
+	require.EqualError(t, err, "Panic while cleaning main.go:3:1: runtime error: invalid memory address or nil pointer dereference")
+}
+
+func TestCleanDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go":              "package a\n\nfunc a() {\n\n\treturn\n}\n",
+		"clean.go":          "package a\n\nfunc b() {\n\treturn\n}\n",
+		"generated.go":      "// Code generated by test. DO NOT EDIT.\n\npackage a\n\nfunc c() {\n\n\treturn\n}\n",
+		"sub/b.go":          "package sub\n\nfunc d() {\n\treturn\n\n}\n",
+		".hidden/c.go":      "package hidden\n\nfunc e() {\n\n\treturn\n}\n",
+		"sub/testdata/d.go": "package testdata\n\nfunc f() {\n\n\treturn\n}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+
+	modified, err := CleanDir(dir, AllMode)
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(dir, "a.go"), filepath.Join(dir, "sub", "b.go")}, modified)
+
+	for name, content := range files {
+		buf, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		require.NoError(t, err)
+		switch name {
+		case "a.go":
+			content = "package a\n\nfunc a() {\n\treturn\n}\n"
+		case "sub/b.go":
+			content = "package sub\n\nfunc d() {\n\treturn\n}\n"
+		}
+		require.Equal(t, content, string(buf), "%s failed", name)
+	}
+
+	_, err = CleanDir(filepath.Join(dir, "a.go"), AllMode)
+	require.EqualError(t, err, "`"+filepath.Join(dir, "a.go")+"' is not a directory")
+}
+
+func TestCleanDirErrors(t *testing.T) {
+	dir := t.TempDir()
+	_, err := CleanDir(filepath.Join(dir, "missing"), AllMode)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	// the underlying errors of files are preserved
+	require.NoError(t, os.Symlink(filepath.Join(dir, "missing.go"), filepath.Join(dir, "a.go")))
+	_, err = CleanDir(dir, AllMode)
+	require.ErrorIs(t, err, os.ErrNotExist)
+	require.Contains(t, err.Error(), filepath.Join(dir, "a.go"))
+}
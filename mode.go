@@ -0,0 +1,129 @@
+package goremovelines
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Mode is a bitmask that defines which lines should be removed.
+// It implements flag.Value, encoding.TextMarshaler and encoding.TextUnmarshaler.
+type Mode int
+
+const (
+	// FuncMode should be set to remove empty lines in functions.
+	FuncMode Mode = 1 << iota
+	// StructMode should be set to remove empty lines in structs.
+	StructMode
+	// IfMode should be set to remove empty lines in if blocks.
+	IfMode
+	// SwitchMode should be set to remove empty lines in switch blocks.
+	SwitchMode
+	// CaseMode should be set to remove empty lines in case blocks.
+	CaseMode
+	// ForMode should be set to remove empty lines in for blocks.
+	ForMode
+	// InterfaceMode should be set to remove empty lines in interface blocks.
+	InterfaceMode
+	// BlockMode should be set to remove empty lines in blocks.
+	BlockMode
+	// AllMode includes all modes.
+	AllMode = FuncMode | StructMode | IfMode | SwitchMode | CaseMode | ForMode | InterfaceMode | BlockMode
+)
+
+const allModeName = "all"
+
+var modeNames = []struct {
+	name string
+	mode Mode
+}{
+	{"func", FuncMode},
+	{"struct", StructMode},
+	{"if", IfMode},
+	{"switch", SwitchMode},
+	{"case", CaseMode},
+	{"for", ForMode},
+	{"interface", InterfaceMode},
+	{"block", BlockMode},
+}
+
+// ParseMode parses a comma separated list of mode names (func, struct, if, switch, case, for, interface, block or all).
+// Names prefixed with `-` are removed from the mode, if the list starts with such a name it is removed from all modes.
+// For example `func,if,-case` and `-case`.
+func ParseMode(s string) (Mode, error) {
+	var mode Mode
+	if strings.TrimSpace(s) == "" {
+		return mode, nil
+	}
+	for i, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		exclude := strings.HasPrefix(name, "-")
+		if exclude {
+			name = name[1:]
+			if i == 0 {
+				mode = AllMode
+			}
+		}
+		m, ok := modeByName(name)
+		if !ok {
+			return 0, errors.Errorf("unknown mode `%s'", name)
+		}
+		if exclude {
+			mode &^= m
+		} else {
+			mode |= m
+		}
+	}
+	return mode, nil
+}
+
+func modeByName(name string) (Mode, bool) {
+	if name == allModeName {
+		return AllMode, true
+	}
+	for _, n := range modeNames {
+		if n.name == name {
+			return n.mode, true
+		}
+	}
+	return 0, false
+}
+
+// Has reports whether all modes of m are set.
+func (mode Mode) Has(m Mode) bool {
+	return mode&m == m
+}
+
+// String returns the comma separated list of mode names, it can be parsed with ParseMode.
+func (mode Mode) String() string {
+	if mode.Has(AllMode) {
+		return allModeName
+	}
+	names := make([]string, 0, len(modeNames))
+	for _, n := range modeNames {
+		if mode.Has(n.mode) {
+			names = append(names, n.name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// Set parses s with ParseMode and replaces the mode, it implements flag.Value.
+func (mode *Mode) Set(s string) error {
+	m, err := ParseMode(s)
+	if err != nil {
+		return err
+	}
+	*mode = m
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (mode Mode) MarshalText() ([]byte, error) {
+	return []byte(mode.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (mode *Mode) UnmarshalText(text []byte) error {
+	return mode.Set(string(text))
+}
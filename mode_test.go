@@ -0,0 +1,69 @@
+package goremovelines
+
+import (
+	"encoding"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ flag.Value               = (*Mode)(nil)
+	_ encoding.TextMarshaler   = Mode(0)
+	_ encoding.TextUnmarshaler = (*Mode)(nil)
+)
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Mode
+	}{
+		{"func", FuncMode},
+		{"func,if", FuncMode | IfMode},
+		{" Func , IF ", FuncMode | IfMode},
+		{"all", AllMode},
+		{"all,-case", AllMode &^ CaseMode},
+		{"-case", AllMode &^ CaseMode},
+		{"func,if,-if", FuncMode},
+		{"", 0},
+	}
+
+	for i, test := range tests {
+		mode, err := ParseMode(test.input)
+		require.NoError(t, err, "Test %d failed", i)
+		require.Equal(t, test.expected, mode, "Test %d failed", i)
+	}
+
+	for _, input := range []string{"fucn", "func,", "func,-fucn", "-"} {
+		_, err := ParseMode(input)
+		require.Error(t, err, "Test `%s' failed", input)
+	}
+}
+
+func TestModeString(t *testing.T) {
+	tests := []struct {
+		input    Mode
+		expected string
+	}{
+		{FuncMode, "func"},
+		{FuncMode | CaseMode | BlockMode, "func,case,block"},
+		{AllMode, "all"},
+		{AllMode &^ CaseMode, "func,struct,if,switch,for,interface,block"},
+		{0, ""},
+	}
+
+	for i, test := range tests {
+		require.Equal(t, test.expected, test.input.String(), "Test %d failed", i)
+
+		var mode Mode
+		require.NoError(t, mode.UnmarshalText([]byte(test.input.String())), "Test %d failed", i)
+		require.Equal(t, test.input, mode, "Test %d failed", i)
+	}
+}
+
+func TestModeHas(t *testing.T) {
+	require.True(t, AllMode.Has(CaseMode))
+	require.True(t, (FuncMode | IfMode).Has(FuncMode|IfMode))
+	require.False(t, FuncMode.Has(FuncMode|IfMode))
+}
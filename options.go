@@ -0,0 +1,220 @@
+package goremovelines
+
+import (
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// Options configure Clean.
+type Options struct {
+	// Mode selects what is cleaned.
+	Mode Mode
+	// Filename is the name of the source, it is only used for positions and error messages.
+	Filename string
+	// Tolerant cleans sources with syntax errors: top-level declarations that contain syntax errors are
+	// left untouched and reported in Result.Skipped, all other declarations are cleaned.
+	// The source still has to start with a valid package clause.
+	Tolerant bool
+	// Fragment accepts sources without a package clause like go/format.Source does:
+	// a list of declarations or a list of statements is cleaned as if it was part of a file or a function.
+	Fragment bool
+	// Gofmt formats the result with go/format. It fails if the formatted result is not stable,
+	// i.e. if cleaning and formatting it again would change it. Sources with skipped declarations are not formatted.
+	Gofmt bool
+	// Verify checks that the cleaned source (before it is formatted) has the same syntax tree
+	// (ignoring positions) and the same comments as the original, otherwise a *VerifyError is returned.
+	Verify bool
+
+	// ignoreDirectives cleans the source as if it had no comment directives, it is used by Explain.
+	ignoreDirectives bool
+	// ctx stops the cleaning when it is done, it is set by CleanContext.
+	ctx context.Context
+}
+
+// canceled returns an error if the context of the options is done.
+func (o *Options) canceled() error {
+	if o.ctx == nil {
+		return nil
+	}
+	if err := o.ctx.Err(); err != nil {
+		if o.Filename != "" {
+			return errors.Wrapf(err, "Cleaning `%s' was stopped", o.Filename)
+		}
+		return errors.Wrap(err, "Cleaning was stopped")
+	}
+	return nil
+}
+
+// Result is the result of Clean.
+type Result struct {
+	// Source is the cleaned source.
+	Source string
+	// Skipped are the declarations that were not cleaned because they contain syntax errors.
+	Skipped []SkippedDecl
+	// Removed are the blank lines that were removed, ordered by line.
+	Removed []Removal
+}
+
+// Removal is a blank line that was removed.
+type Removal struct {
+	// Line is the line of the removed blank line in the original source.
+	Line int
+	// Mode is the mode that removed the line.
+	Mode Mode
+	// Node is the type of the construct whose blank line was removed, e.g. *ast.FuncDecl.
+	Node string
+}
+
+// SkippedDecl is a top-level declaration that was not cleaned in tolerant mode.
+type SkippedDecl struct {
+	// Pos and End are the positions of the declaration in the cleaned source.
+	Pos token.Position
+	End token.Position
+	// Errors are the syntax errors inside the declaration.
+	Errors scanner.ErrorList
+}
+
+// Clean cleans the source with the options.
+func Clean(src string, opts Options) (*Result, error) {
+	return CleanContext(context.Background(), src, opts)
+}
+
+// CleanContext cleans the source with the options like Clean, the cleaning is stopped when ctx is done.
+// The returned error wraps the error of ctx then.
+func CleanContext(ctx context.Context, src string, opts Options) (*Result, error) {
+	opts.ctx = ctx
+	result, err := clean(&src, &opts)
+	if err != nil {
+		return nil, err
+	}
+	result.Source = src
+	return result, nil
+}
+
+// topLevelDecl matches the lines that start a top-level declaration.
+var topLevelDecl = regexp.MustCompile(`(?m)^(func|type|var|const|import)\b`)
+
+// parseTolerant parses src, the top-level declarations that contain syntax errors are replaced with spaces
+// (keeping the line breaks so all positions stay the same) until the rest of the source can be parsed.
+// The comments in front of the next declaration (e.g. its doc comment and directives) are not replaced.
+// It returns the file of the remaining declarations and the declarations that were replaced.
+func parseTolerant(src string, opts *Options) (*token.FileSet, *ast.File, []SkippedDecl, error) {
+	filename := opts.Filename
+	chunks := topLevelDecl.FindAllStringIndex(src, -1)
+	comments := commentEnds(src)
+	masked := []byte(src)
+	isMasked := make([]bool, len(chunks))
+	var skipped []SkippedDecl
+	for {
+		if err := opts.canceled(); err != nil {
+			return nil, nil, nil, err
+		}
+		set := token.NewFileSet()
+		astFile, err := parser.ParseFile(set, filename, masked, parser.ParseComments|parser.AllErrors)
+		if err == nil {
+			sort.Slice(skipped, func(i, j int) bool {
+				return skipped[i].Pos.Offset < skipped[j].Pos.Offset
+			})
+			return set, astFile, skipped, nil
+		}
+		parseErr := newParseError(filename, err)
+		if astFile == nil || !astFile.Package.IsValid() {
+			return nil, nil, nil, parseErr
+		}
+		file := set.File(astFile.Package)
+
+		// only the declaration of the first error is replaced, the following errors are often caused by it
+		first := parseErr.Errors[0].Pos.Offset
+		found := false
+		for i, chunk := range chunks {
+			start := chunk[0]
+			// errors at the end of the source belong to the last declaration
+			end := len(src) + 1
+			if i+1 < len(chunks) {
+				end = chunks[i+1][0]
+			}
+			if isMasked[i] || first < start || first >= end {
+				continue
+			}
+			if end > len(src) {
+				end = len(src)
+			}
+
+			var list scanner.ErrorList
+			for _, e := range parseErr.Errors {
+				if e.Pos.Offset >= start && e.Pos.Offset <= end {
+					list = append(list, e)
+				}
+			}
+			found = true
+			isMasked[i] = true
+			end = codeEnd(src, comments, start, end)
+			for j := start; j < end; j++ {
+				if masked[j] != '\n' {
+					masked[j] = ' '
+				}
+			}
+			skipped = append(skipped, SkippedDecl{
+				Pos:    file.Position(file.Pos(start)),
+				End:    file.Position(file.Pos(end)),
+				Errors: list,
+			})
+			break
+		}
+		if !found {
+			// the errors are not inside a declaration (e.g. in the package clause)
+			return nil, nil, nil, parseErr
+		}
+	}
+}
+
+// commentEnds returns the start offsets of all comments of src by their end offsets.
+func commentEnds(src string) map[int]int {
+	set := token.NewFileSet()
+	file := set.AddFile("", set.Base(), len(src))
+	var s scanner.Scanner
+	s.Init(file, []byte(src), nil, scanner.ScanComments)
+	ends := make(map[int]int)
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok != token.COMMENT {
+			continue
+		}
+		start := file.Offset(pos)
+		end := len(src)
+		if strings.HasPrefix(lit, "//") {
+			if i := strings.IndexByte(src[start:], '\n'); i >= 0 {
+				end = start + i
+			}
+		} else if i := strings.Index(src[start+2:], "*/"); i >= 0 {
+			end = start + 2 + i + 2
+		}
+		end = start + len(strings.TrimRightFunc(src[start:end], unicode.IsSpace))
+		ends[end] = start
+	}
+	return ends
+}
+
+// codeEnd returns the end of the code between start and end, without the white space and the comments at the end.
+func codeEnd(src string, comments map[int]int, start, end int) int {
+	for {
+		end = start + len(strings.TrimRightFunc(src[start:end], unicode.IsSpace))
+		commentStart, ok := comments[end]
+		if !ok || commentStart <= start {
+			return end
+		}
+		end = commentStart
+	}
+}
@@ -0,0 +1,193 @@
+package goremovelines
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanTolerant(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+		skipped  [][2]int
+	}{
+		{
+			"package a\n\nfunc a() {\n\n\treturn\n}\n",
+			"package a\n\nfunc a() {\n\treturn\n}\n",
+			nil,
+		},
+		{
+			"package a\n\nfunc a() {\n\n\tb(\n\n}\n\ntype T struct {\n\n\tA int\n}\n\nfunc c() {\n\n\treturn\n}\n",
+			"package a\n\nfunc a() {\n\n\tb(\n\n}\n\ntype T struct {\n\tA int\n}\n\nfunc c() {\n\treturn\n}\n",
+			[][2]int{{3, 7}},
+		},
+		{
+			"package a\n\nfunc a() {\n\n\treturn\n}\n\nfunc b() {\n\n\tif {\n}\n",
+			"package a\n\nfunc a() {\n\treturn\n}\n\nfunc b() {\n\n\tif {\n}\n",
+			[][2]int{{7, 10}},
+		},
+		{
+			// the doc comment and the directives of the next declaration are not part of the skipped declaration
+			"package a\n\nfunc a() {\n\tb(\n} // a\n\n// c is kept.\n/* multi\nline */\n//goremovelines:ignore\n" +
+				"func c() {\n\n\treturn\n}\n\nfunc d() {\n\n\treturn\n}\n",
+			"package a\n\nfunc a() {\n\tb(\n} // a\n\n// c is kept.\n/* multi\nline */\n//goremovelines:ignore\n" +
+				"func c() {\n\n\treturn\n}\n\nfunc d() {\n\treturn\n}\n",
+			[][2]int{{3, 5}},
+		},
+		{
+			"package a\n\nvar = 1\n\nfunc a() {\n\n\treturn\n}\n\nconst x =\n",
+			"package a\n\nvar = 1\n\nfunc a() {\n\treturn\n}\n\nconst x =\n",
+			[][2]int{{3, 3}, {9, 9}},
+		},
+	}
+
+	for i, test := range tests {
+		result, err := Clean(test.src, Options{Mode: AllMode, Filename: "a.go", Tolerant: true})
+		require.NoError(t, err, "Test %d failed", i)
+		require.Equal(t, test.expected, result.Source, "Test %d failed", i)
+		require.Len(t, result.Skipped, len(test.skipped), "Test %d failed", i)
+		for j, skipped := range result.Skipped {
+			require.Equal(t, "a.go", skipped.Pos.Filename, "Test %d failed", i)
+			require.Equal(t, test.skipped[j][0], skipped.Pos.Line, "Test %d failed", i)
+			require.Equal(t, test.skipped[j][1], skipped.End.Line, "Test %d failed", i)
+			require.NotEmpty(t, skipped.Errors, "Test %d failed", i)
+		}
+	}
+}
+
+func TestCleanTolerantErrors(t *testing.T) {
+	tests := []string{
+		"packag a\n\nfunc a() {\n}\n",
+		"package\n\nfunc a() {\n}\n",
+	}
+
+	for i, test := range tests {
+		_, err := Clean(test, Options{Mode: AllMode, Tolerant: true})
+		var parseErr *ParseError
+		require.True(t, errors.As(err, &parseErr), "Test %d failed", i)
+	}
+
+	_, err := Clean("package a\n\nfunc a() {\n", Options{Mode: AllMode})
+	var parseErr *ParseError
+	require.True(t, errors.As(err, &parseErr))
+}
+
+func TestCleanGofmt(t *testing.T) {
+	tests := []struct {
+		opts     Options
+		src      string
+		expected string
+	}{
+		{
+			Options{Mode: AllMode, Gofmt: true},
+			"package a\nfunc a() {\n\n  x:=1\n  _ = x\n\n}\n",
+			"package a\n\nfunc a() {\n\tx := 1\n\t_ = x\n}\n",
+		},
+		{
+			Options{Mode: IfMode, Gofmt: true},
+			"package a\nfunc a() {\n\n\tif true {\n\n\t}\n}\n",
+			"package a\n\nfunc a() {\n\n\tif true {\n\t}\n}\n",
+		},
+		{
+			Options{Mode: AllMode, Gofmt: true, Fragment: true},
+			"\tif x  {\n\n\t\ty( )\n\t}\n",
+			"\tif x {\n\t\ty()\n\t}\n",
+		},
+		{
+			Options{Mode: AllMode, Gofmt: true, Tolerant: true},
+			"package a\nfunc a() {\n\n\tb(\n}\n",
+			"package a\nfunc a() {\n\n\tb(\n}\n",
+		},
+	}
+
+	for i, test := range tests {
+		result, err := Clean(test.src, test.opts)
+		require.NoError(t, err, "Test %d failed", i)
+		require.Equal(t, test.expected, result.Source, "Test %d failed", i)
+	}
+}
+
+func TestCleanGofmtStable(t *testing.T) {
+	defer func(f func([]byte) ([]byte, error)) {
+		formatter = f
+	}(formatter)
+
+	// a formatter that changes its own output is not stable
+	formatter = func(src []byte) ([]byte, error) {
+		return append(src, "// formatted\n"...), nil
+	}
+	_, err := Clean("package a\n", Options{Mode: AllMode, Gofmt: true, Filename: "a.go"})
+	require.EqualError(t, err, "Result of `a.go' is not stable under a second pass")
+}
+
+func TestCleanRemoved(t *testing.T) {
+	tests := []struct {
+		src      string
+		opts     Options
+		expected []Removal
+	}{
+		{
+			"package a\n\nfunc a() {\n\n\n\tfor {\n\n\t\tb()\n\t}\n\n}\n",
+			Options{Mode: AllMode},
+			[]Removal{
+				{4, FuncMode, "*ast.FuncDecl"},
+				{5, FuncMode, "*ast.FuncDecl"},
+				{7, ForMode, "*ast.ForStmt"},
+				{10, FuncMode, "*ast.FuncDecl"},
+			},
+		},
+		{
+			"package a\n\ntype T struct {\n\n\tA int\n}\n\nfunc a() {\n\tswitch {\n\tcase true:\n\n\t\tb()\n\t}\n}\n",
+			Options{Mode: AllMode &^ StructMode},
+			[]Removal{
+				{11, CaseMode, "*ast.CaseClause"},
+			},
+		},
+		{
+			"func a() {\n\n\tb()\n}\n",
+			Options{Mode: AllMode, Fragment: true},
+			[]Removal{
+				{2, FuncMode, "*ast.FuncDecl"},
+			},
+		},
+		{
+			"package a\n\nfunc a() {\n\treturn\n}\n",
+			Options{Mode: AllMode},
+			nil,
+		},
+	}
+
+	for i, test := range tests {
+		result, err := Clean(test.src, test.opts)
+		require.NoError(t, err, "Test %d failed", i)
+		require.Equal(t, test.expected, result.Removed, "Test %d failed", i)
+	}
+}
+
+func TestCleanContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, opts := range []Options{{Mode: AllMode}, {Mode: AllMode, Tolerant: true, Filename: "a.go"}} {
+		_, err := CleanContext(ctx, "package a\nfunc a() {\n\n}\n", opts)
+		require.ErrorIs(t, err, context.Canceled)
+	}
+
+	// the cleaning stops while it is running, every pass of this source parses the whole source
+	src := "package a\nfunc a() {\n" + strings.Repeat("\n", 1000) + strings.Repeat("\tx()\n", 5000) + "}\n"
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := CleanContext(ctx, src, Options{Mode: AllMode})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.EqualError(t, err, "Cleaning was stopped: context deadline exceeded")
+	require.Less(t, time.Since(start), time.Second)
+
+	result, err := CleanContext(context.Background(), "package a\nfunc a() {\n\n}\n", Options{Mode: AllMode})
+	require.NoError(t, err)
+	require.Equal(t, "package a\nfunc a() {\n}\n", result.Source)
+}
@@ -0,0 +1,132 @@
+package goremovelines
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+)
+
+var (
+	posType          = reflect.TypeOf(token.NoPos)
+	commentGroupType = reflect.TypeOf(&ast.CommentGroup{})
+	commentsType     = reflect.TypeOf([]*ast.CommentGroup{})
+	nodeType         = reflect.TypeOf((*ast.Node)(nil)).Elem()
+)
+
+// verify checks that result has the same syntax tree (ignoring positions) and the same comments as original.
+func verify(original, result string, opts *Options) error {
+	origSet, origFile, origSkipped, err := parseForVerify(original, opts)
+	if err != nil {
+		return err
+	}
+	resultSet, resultFile, resultSkipped, err := parseForVerify(result, opts)
+	if err != nil {
+		return &VerifyError{Msg: "the result can not be parsed: " + err.Error()}
+	}
+
+	// declarations with syntax errors must not change at all
+	if len(origSkipped) != len(resultSkipped) {
+		return &VerifyError{Msg: "the declarations with syntax errors changed"}
+	}
+	for i := range origSkipped {
+		a := original[origSkipped[i].Pos.Offset:origSkipped[i].End.Offset]
+		b := result[resultSkipped[i].Pos.Offset:resultSkipped[i].End.Offset]
+		if a != b {
+			return &VerifyError{Pos: origSkipped[i].Pos, Msg: "the declaration with syntax errors changed"}
+		}
+	}
+
+	c := comparer{pos: origFile.Pos()}
+	if !c.equal(reflect.ValueOf(origFile), reflect.ValueOf(resultFile)) {
+		return &VerifyError{Pos: origSet.Position(c.pos), Msg: "the syntax tree changed"}
+	}
+
+	origComments := comments(origFile)
+	resultComments := comments(resultFile)
+	for i := 0; i < len(origComments) || i < len(resultComments); i++ {
+		switch {
+		case i >= len(origComments):
+			return &VerifyError{Pos: resultSet.Position(resultComments[i].Pos()), Msg: "a comment was added"}
+		case i >= len(resultComments):
+			return &VerifyError{Pos: origSet.Position(origComments[i].Pos()), Msg: "a comment was removed"}
+		case origComments[i].Text != resultComments[i].Text:
+			return &VerifyError{Pos: origSet.Position(origComments[i].Pos()), Msg: "a comment changed"}
+		}
+	}
+	return nil
+}
+
+func parseForVerify(src string, opts *Options) (*token.FileSet, *ast.File, []SkippedDecl, error) {
+	if opts.Tolerant {
+		return parseTolerant(src, opts)
+	}
+	set := token.NewFileSet()
+	astFile, err := parser.ParseFile(set, opts.Filename, src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, nil, nil, newParseError(opts.Filename, err)
+	}
+	return set, astFile, nil, nil
+}
+
+func comments(astFile *ast.File) []*ast.Comment {
+	var list []*ast.Comment
+	for _, group := range astFile.Comments {
+		list = append(list, group.List...)
+	}
+	return list
+}
+
+// comparer compares syntax trees, pos is the position of the last node of the first tree that was compared.
+type comparer struct {
+	pos token.Pos
+}
+
+// equal reports whether a and b are equal, positions, comments and resolved objects are ignored.
+func (c *comparer) equal(a, b reflect.Value) bool {
+	if a.Type() != b.Type() {
+		return false
+	}
+	if a.Type().Implements(nodeType) && a.Kind() == reflect.Ptr && !a.IsNil() {
+		if pos := a.Interface().(ast.Node).Pos(); pos.IsValid() {
+			c.pos = pos
+		}
+	}
+
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		return c.equal(a.Elem(), b.Elem())
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			field := a.Type().Field(i)
+			switch {
+			case field.Type == posType, field.Type == commentGroupType, field.Type == commentsType:
+				continue
+			case field.Type == reflect.TypeOf(&ast.Scope{}), field.Type == reflect.TypeOf(&ast.Object{}):
+				continue
+			}
+			if !c.equal(a.Field(i), b.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Slice:
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !c.equal(a.Index(i), b.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		// only ast.Scope and ast.Package contain maps, scopes are skipped
+		return a.Len() == b.Len()
+	default:
+		return a.Interface() == b.Interface()
+	}
+}
@@ -0,0 +1,84 @@
+package goremovelines
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		original string
+		result   string
+		expected string
+	}{
+		{
+			"package a\n\nfunc a() {\n\n\treturn\n}\n",
+			"package a\n\nfunc a() {\n\treturn\n}\n",
+			"",
+		},
+		{
+			"package a\n\n// a does nothing.\nfunc a() {\n\n\t// nothing\n\n}\n",
+			"package a\n\n// a does nothing.\nfunc a() {\n\t// nothing\n}\n",
+			"",
+		},
+		{
+			"package a\n\nfunc a() {\n\n\treturn\n}\n",
+			"package a\n\nfunc a() {\n\treturn 1\n}\n",
+			"Verification failed at a.go:5:2: the syntax tree changed",
+		},
+		{
+			"package a\n\nfunc a() {\n\tx := 1\n\t_ = x\n}\n",
+			"package a\n\nfunc a() {\n\tx := 2\n\t_ = x\n}\n",
+			"Verification failed at a.go:4:7: the syntax tree changed",
+		},
+		{
+			"package a\n\nfunc a() {\n\n\t// nothing\n}\n",
+			"package a\n\nfunc a() {\n\t// something\n}\n",
+			"Verification failed at a.go:5:2: a comment changed",
+		},
+		{
+			"package a\n\nfunc a() {\n\n\t// nothing\n}\n",
+			"package a\n\nfunc a() {\n}\n",
+			"Verification failed at a.go:5:2: a comment was removed",
+		},
+		{
+			"package a\n\nfunc a() {\n}\n",
+			"package a\n\nfunc a() {\n\t/* new */\n}\n",
+			"Verification failed at a.go:4:2: a comment was added",
+		},
+		{
+			"package a\n\nfunc a() {\n}\n",
+			"package a\n\nfunc a() {\n",
+			"Verification failed: the result can not be parsed: a.go:3:12: expected '}', found 'EOF'",
+		},
+	}
+
+	for i, test := range tests {
+		err := verify(test.original, test.result, &Options{Filename: "a.go"})
+		if test.expected == "" {
+			require.NoError(t, err, "Test %d failed", i)
+			continue
+		}
+		var verifyErr *VerifyError
+		require.True(t, errors.As(err, &verifyErr), "Test %d failed", i)
+		require.EqualError(t, err, test.expected, "Test %d failed", i)
+	}
+}
+
+func TestVerifyTolerant(t *testing.T) {
+	opts := &Options{Filename: "a.go", Tolerant: true}
+	original := "package a\n\nfunc a() {\n\n\tb(\n}\n\nfunc c() {\n\n}\n"
+	require.NoError(t, verify(original, "package a\n\nfunc a() {\n\n\tb(\n}\n\nfunc c() {\n}\n", opts))
+	require.EqualError(t, verify(original, "package a\n\nfunc a() {\n\tb(\n}\n\nfunc c() {\n}\n", opts),
+		"Verification failed at a.go:3:1: the declaration with syntax errors changed")
+
+	result, err := Clean(original, Options{Mode: AllMode, Tolerant: true, Fragment: true, Verify: true, Gofmt: true})
+	require.NoError(t, err)
+	require.Equal(t, "package a\n\nfunc a() {\n\n\tb(\n}\n\nfunc c() {\n}\n", result.Source)
+
+	result, err = Clean("if x {\n\n\ty()\n}\n", Options{Mode: AllMode, Fragment: true, Verify: true})
+	require.NoError(t, err)
+	require.Equal(t, "if x {\n\ty()\n}\n", result.Source)
+}
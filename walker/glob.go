@@ -0,0 +1,136 @@
+package walker
+
+import (
+	"path"
+	"strings"
+)
+
+// Pattern is a glob pattern that is matched against slash separated paths relative to Dir.
+// Besides the syntax of path.Match, `**` matches any number of path segments and
+// `{a,b}` matches one of the comma separated alternatives.
+type Pattern struct {
+	// Dir is the directory the pattern is relative to,
+	// if it is empty the pattern is relative to the root of the walk.
+	Dir string
+	// Glob is the pattern.
+	Glob string
+}
+
+// NewPatterns returns a Pattern for every glob, all relative to dir.
+func NewPatterns(dir string, globs []string) []Pattern {
+	result := make([]Pattern, 0, len(globs))
+	for _, glob := range globs {
+		result = append(result, Pattern{Dir: dir, Glob: glob})
+	}
+	return result
+}
+
+// pathPattern is a Pattern where base is resolved to a name of the walked file system.
+type pathPattern struct {
+	base    string
+	pattern string
+}
+
+func (p pathPattern) rel(name string) (string, bool) {
+	return relName(p.base, name)
+}
+
+// match reports whether the name matches the pattern.
+func (p pathPattern) match(name string) bool {
+	rel, ok := p.rel(name)
+	if !ok {
+		return false
+	}
+	return matchGlob(p.pattern, rel)
+}
+
+// matchBelow reports whether the pattern could match a path inside the directory dir.
+func (p pathPattern) matchBelow(dir string) bool {
+	rel, ok := p.rel(dir)
+	if !ok {
+		return false
+	}
+	if rel == "." {
+		return true
+	}
+	for _, pattern := range expandBraces(p.pattern) {
+		if matchSegments(strings.Split(pattern, "/"), strings.Split(rel, "/"), true) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAny(patterns []pathPattern, name string) bool {
+	for _, p := range patterns {
+		if p.match(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAnyBelow(patterns []pathPattern, dir string) bool {
+	for _, p := range patterns {
+		if p.matchBelow(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob reports whether the slash separated name matches pattern.
+func matchGlob(pattern, name string) bool {
+	for _, pattern := range expandBraces(pattern) {
+		if matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"), false) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments matches the path segments against the pattern segments,
+// if prefix is set it reports whether a path that starts with the segments could match.
+func matchSegments(pattern, segments []string, prefix bool) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			if prefix {
+				return true
+			}
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:], prefix) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return prefix
+		}
+		if ok, err := path.Match(pattern[0], segments[0]); err != nil || !ok {
+			return false
+		}
+		pattern = pattern[1:]
+		segments = segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// expandBraces expands the first `{a,b}` group of pattern (recursively).
+func expandBraces(pattern string) []string {
+	start := strings.IndexByte(pattern, '{')
+	if start == -1 {
+		return []string{pattern}
+	}
+	end := strings.IndexByte(pattern[start:], '}')
+	if end == -1 {
+		return []string{pattern}
+	}
+	end += start
+
+	var result []string
+	for _, alternative := range strings.Split(pattern[start+1:end], ",") {
+		result = append(result, expandBraces(pattern[:start]+alternative+pattern[end+1:])...)
+	}
+	return result
+}
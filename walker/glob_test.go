@@ -0,0 +1,55 @@
+package walker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/main.go", false},
+		{"**/*.go", "main.go", true},
+		{"**/*.go", "cmd/goremovelines/main.go", true},
+		{"**/*_mock.go", "internal/db/db_mock.go", true},
+		{"**/*_mock.go", "internal/db/db.go", false},
+		{"api/gen/**", "api/gen/v1/api.go", true},
+		{"api/gen/**", "api/gen", true},
+		{"api/gen/**", "api/other/api.go", false},
+		{"**/testdata", "pkg/testdata", true},
+		{"**/testdata/**", "pkg/testdata/in.go", true},
+		{"{api,web}/**/*.go", "web/x/main.go", true},
+		{"{api,web}/**/*.go", "cmd/main.go", false},
+		{"[", "[", false},
+	}
+
+	for i, test := range tests {
+		require.Equal(t, test.expected, matchGlob(test.pattern, test.name), "Test %d failed", i)
+	}
+}
+
+func TestPathPatternMatchBelow(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		dir      string
+		expected bool
+	}{
+		{"_legacy/**", "root/_legacy", true},
+		{"_legacy/*.go", "root/_legacy", true},
+		{"_legacy/*.go", "root/_legacy/sub", false},
+		{"**/*.go", "root/a/b", true},
+		{"api/gen/*.go", "root/web", false},
+		{"*.go", "root", true},
+		{"*.go", "other", false},
+	}
+
+	for i, test := range tests {
+		p := pathPattern{base: "root", pattern: test.pattern}
+		require.Equal(t, test.expected, p.matchBelow(test.dir), "Test %d failed", i)
+	}
+}
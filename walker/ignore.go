@@ -0,0 +1,150 @@
+package walker
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// IgnoreFileName is the name of the tool specific ignore file, it uses the same syntax as .gitignore.
+const IgnoreFileName = ".goremovelinesignore"
+
+// ignoreRule is one pattern of an ignore file.
+type ignoreRule struct {
+	base     string
+	segments []string
+	negate   bool
+	dirOnly  bool
+}
+
+func (r ignoreRule) match(name string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	rel, ok := relName(r.base, name)
+	if !ok || rel == "." {
+		return false
+	}
+	return matchSegments(r.segments, strings.Split(rel, "/"), false)
+}
+
+// parseIgnoreFile parses the content of a .gitignore file, the patterns are relative to base.
+func parseIgnoreFile(base string, content []byte) []ignoreRule {
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasSuffix(line, "\\ ") {
+			line = strings.TrimRight(line[:len(line)-2], " ") + " "
+		} else {
+			line = strings.TrimRight(line, " \t\r")
+		}
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		rule := ignoreRule{base: base}
+		if line[0] == '!' {
+			rule.negate = true
+			line = line[1:]
+		} else if line[0] == '\\' {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		// patterns without a slash match at any level below base
+		if !strings.Contains(line, "/") {
+			line = "**/" + line
+		}
+		rule.segments = strings.Split(strings.TrimPrefix(line, "/"), "/")
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// ignoreMatcher reports whether paths are ignored by .gitignore, .git/info/exclude and .goremovelinesignore files.
+// Like git, the ignore files of a directory and all its parents up to the repository root are used,
+// the last matching pattern wins.
+type ignoreMatcher struct {
+	fsys  fs.FS
+	cache map[string][]ignoreRule
+}
+
+func newIgnoreMatcher(fsys fs.FS) *ignoreMatcher {
+	return &ignoreMatcher{fsys: fsys, cache: make(map[string][]ignoreRule)}
+}
+
+// rules returns the rules that apply to the entries of the directory dir.
+func (m *ignoreMatcher) rules(dir string) ([]ignoreRule, error) {
+	if rules, ok := m.cache[dir]; ok {
+		return rules, nil
+	}
+
+	var rules []ignoreRule
+	isRepositoryRoot := false
+	if fi, err := fs.Stat(m.fsys, path.Join(dir, ".git")); err == nil {
+		isRepositoryRoot = true
+		if fi.IsDir() {
+			excludeRules, err := m.readIgnoreFile(dir, path.Join(dir, ".git", "info", "exclude"))
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, excludeRules...)
+		}
+	}
+	if parent := path.Dir(dir); parent != dir && !isRepositoryRoot {
+		parentRules, err := m.rules(parent)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(parentRules[:len(parentRules):len(parentRules)], rules...)
+	}
+	for _, name := range []string{".gitignore", IgnoreFileName} {
+		fileRules, err := m.readIgnoreFile(dir, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, fileRules...)
+	}
+
+	m.cache[dir] = rules
+	return rules, nil
+}
+
+// match reports whether name is ignored.
+func (m *ignoreMatcher) match(name string, isDir bool) (bool, error) {
+	if m == nil {
+		return false, nil
+	}
+	rules, err := m.rules(path.Dir(name))
+	if err != nil {
+		return false, err
+	}
+	ignored := false
+	for _, rule := range rules {
+		if rule.match(name, isDir) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored, nil
+}
+
+func (m *ignoreMatcher) readIgnoreFile(base, name string) ([]ignoreRule, error) {
+	content, err := fs.ReadFile(m.fsys, name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("unable to read `%s': %w", name, err)
+	}
+	return parseIgnoreFile(base, content), nil
+}
@@ -0,0 +1,48 @@
+package walker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIgnoreRules(t *testing.T) {
+	rules := parseIgnoreFile("repo", []byte(`# build output
+build/
+*.pb.go
+!keep.pb.go
+/root.go
+docs/**/*.go
+\#hash.go
+trailing.go   
+`))
+
+	tests := []struct {
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"repo/build", true, true},
+		{"repo/pkg/build", true, true},
+		{"repo/build", false, false},
+		{"repo/api/api.pb.go", false, true},
+		{"repo/api/keep.pb.go", false, false},
+		{"repo/root.go", false, true},
+		{"repo/pkg/root.go", false, false},
+		{"repo/docs/a/b/c.go", false, true},
+		{"repo/docs/c.go", false, true},
+		{"repo/#hash.go", false, true},
+		{"repo/trailing.go", false, true},
+		{"other/api.pb.go", false, false},
+	}
+
+	for i, test := range tests {
+		ignored := false
+		for _, rule := range rules {
+			if rule.match(test.path, test.isDir) {
+				ignored = !rule.negate
+			}
+		}
+		require.Equal(t, test.expected, ignored, "Test %d (%s) failed", i, test.path)
+	}
+}
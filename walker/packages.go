@@ -0,0 +1,163 @@
+package walker
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/build"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// listedPackage contains the fields of `go list -json` that are needed to find the files of a package.
+type listedPackage struct {
+	ImportPath     string
+	Dir            string
+	GoFiles        []string
+	CgoFiles       []string
+	TestGoFiles    []string
+	XTestGoFiles   []string
+	IgnoredGoFiles []string
+	Error          *struct {
+		Err string
+	}
+}
+
+// goList resolves the package patterns with `go list` and returns the go files of all matched packages.
+// If no tags are set, files that are excluded by build constraints are also returned.
+func (w *Walker) goList(patterns []string) ([]string, error) {
+	args := []string{"list", "-e", "-find", "-json=ImportPath,Dir,GoFiles,CgoFiles,TestGoFiles,XTestGoFiles,IgnoredGoFiles,Error"}
+	if len(w.Tags) > 0 {
+		args = append(args, "-tags="+strings.Join(w.Tags, ","))
+	}
+	args = append(args, "--")
+	args = append(args, patterns...)
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("go", args...) //nolint:gosec // the patterns are passed by the user
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("unable to run go list: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if stderr.Len() > 0 {
+		w.warnf("go list: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	var files []string
+	dec := json.NewDecoder(&stdout)
+	for {
+		var pkg listedPackage
+		if err := dec.Decode(&pkg); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("unable to parse go list output: %w", err)
+		}
+		names := make([]string, 0, len(pkg.GoFiles)+len(pkg.CgoFiles)+len(pkg.TestGoFiles)+len(pkg.XTestGoFiles))
+		names = append(names, pkg.GoFiles...)
+		names = append(names, pkg.CgoFiles...)
+		names = append(names, pkg.TestGoFiles...)
+		names = append(names, pkg.XTestGoFiles...)
+		if len(w.Tags) == 0 {
+			names = append(names, pkg.IgnoredGoFiles...)
+		}
+		if len(names) == 0 && pkg.Error != nil {
+			return nil, fmt.Errorf("unable to list `%s': %s", pkg.ImportPath, pkg.Error.Err)
+		}
+		w.debugf("package %s", pkg.ImportPath)
+		for _, name := range names {
+			files = append(files, filepath.Join(pkg.Dir, name))
+		}
+	}
+	return files, nil
+}
+
+// matchBuildTags reports whether the file name would be built with the tags (and the current GOOS / GOARCH).
+func (t *walk) matchBuildTags(name string) bool {
+	ctx := build.Default
+	ctx.BuildTags = t.w.Tags
+	ctx.JoinPath = path.Join
+	ctx.OpenFile = func(name string) (io.ReadCloser, error) {
+		return t.fsys.Open(name)
+	}
+	ok, err := ctx.MatchFile(path.Dir(name), path.Base(name))
+	if err != nil {
+		// let the cleaner report the error
+		return true
+	}
+	return ok
+}
+
+// isModuleRoot reports whether the directory dir contains a go.mod file.
+func (t *walk) isModuleRoot(dir string) bool {
+	fi, err := fs.Stat(t.fsys, path.Join(dir, "go.mod"))
+	return err == nil && !fi.IsDir()
+}
+
+// workspaceModules returns the directories of the modules that are used by
+// the go.work file of dir (or one of its parents).
+func (t *walk) workspaceModules(dir string) (map[string]bool, error) {
+	modules := make(map[string]bool)
+	if os.Getenv("GOWORK") == "off" {
+		return modules, nil
+	}
+	for {
+		name := path.Join(dir, "go.work")
+		buf, err := fs.ReadFile(t.fsys, name)
+		if err == nil {
+			uses, err := parseWorkUses(bytes.NewReader(buf))
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse `%s': %w", t.path(name), err)
+			}
+			for _, use := range uses {
+				if module, ok := t.resolve(dir, use); ok {
+					modules[module] = true
+				}
+			}
+			return modules, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		parent := path.Dir(dir)
+		if parent == dir {
+			return modules, nil
+		}
+		dir = parent
+	}
+}
+
+// parseWorkUses returns the directories of the use directives in a go.work file.
+func parseWorkUses(r io.Reader) ([]string, error) {
+	var uses []string
+	inBlock := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.NewReplacer("(", " ( ", ")", " ) ").Replace(line)
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+		case inBlock && fields[0] == ")":
+			inBlock = false
+		case inBlock:
+			uses = append(uses, strings.Trim(fields[0], "\"`"))
+		case fields[0] == "use" && len(fields) > 1 && fields[1] == "(":
+			inBlock = true
+		case fields[0] == "use" && len(fields) > 1:
+			uses = append(uses, strings.Trim(fields[1], "\"`"))
+		}
+	}
+	return uses, scanner.Err()
+}
@@ -0,0 +1,26 @@
+package walker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWorkUses(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"go 1.20\n\nuse ./a\n", []string{"./a"}},
+		{"go 1.20\n\nuse (\n\t./a // comment\n\t\"./b\"\n)\n", []string{"./a", "./b"}},
+		{"go 1.20\n\nuse(\n\t.\n)\nreplace x => y\n", []string{"."}},
+		{"go 1.20\n", nil},
+	}
+
+	for i, test := range tests {
+		uses, err := parseWorkUses(strings.NewReader(test.input))
+		require.NoError(t, err, "Test %d failed", i)
+		require.Equal(t, test.expected, uses, "Test %d failed", i)
+	}
+}
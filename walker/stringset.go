@@ -1,6 +1,6 @@
 // taken from gometalinter
 
-package main
+package walker
 
 type stringSet struct {
 	items map[string]struct{}
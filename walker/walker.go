@@ -0,0 +1,391 @@
+// Package walker expands files, directories and package patterns into the go files that should be cleaned.
+//
+// Like the go tool it does not descend into testdata and vendor directories or into other modules
+// (unless they are part of the go.work workspace). Files and directories can be skipped by name,
+// by glob patterns, by .gitignore, .git/info/exclude and .goremovelinesignore files and by build constraints.
+package walker
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Filter decides which entries of a directory are walked.
+type Filter struct {
+	// Skip is the list of file and directory names (or paths) to skip when walking a directory.
+	Skip []string
+	// SkipHidden skips files and directories starting with `_' or `.' when walking a directory.
+	SkipHidden bool
+	// Include limits the files to the ones matching one of the patterns.
+	// Skipped directories are still walked if a pattern could match a file inside them.
+	Include []Pattern
+	// Exclude skips the files and directories matching one of the patterns.
+	Exclude []Pattern
+}
+
+// Walker expands files, directories and package patterns into go files.
+type Walker struct {
+	// FS is the file system to walk, paths are slash separated names as described by io/fs.
+	// If it is nil the file system of the operating system is used, paths are file paths and
+	// patterns that do not exist on disk are resolved as package patterns with `go list`.
+	FS fs.FS
+	// Filter is used for all directories if FilterFor is nil.
+	Filter Filter
+	// FilterFor returns the filter for the entries of the directory dir.
+	FilterFor func(dir string) (Filter, error)
+	// IgnoreFiles skips the paths that are ignored by .gitignore, .git/info/exclude and .goremovelinesignore files.
+	IgnoreFiles bool
+	// Tags limits the files to the ones that would be built with the build tags,
+	// if it is empty build constraints are ignored.
+	Tags []string
+	// Debugf and Warnf receive debug and warning messages, they can be nil.
+	Debugf func(format string, args ...interface{})
+	Warnf  func(format string, args ...interface{})
+}
+
+// New returns a Walker for the file system of the operating system that skips hidden paths and honors ignore files.
+func New() *Walker {
+	return &Walker{
+		Filter:      Filter{SkipHidden: true},
+		IgnoreFiles: true,
+	}
+}
+
+// Walk calls fn for every go file of pattern, the files of a directory are passed in lexical order.
+// The pattern is a file, a directory, a directory followed by `/...` to include all subdirectories or
+// (if FS is nil) a package pattern.
+func (w *Walker) Walk(pattern string, fn func(path string) error) error {
+	var packages []string
+	if err := w.walk(pattern, fn, &packages); err != nil {
+		return err
+	}
+	return w.walkPackages(packages, fn)
+}
+
+// Resolve returns the sorted go files of all patterns, every file is only returned once.
+// If no pattern is passed the current directory is used.
+func (w *Walker) Resolve(patterns []string) ([]string, error) {
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+
+	files := newStringSet()
+	add := func(path string) error {
+		files.add(path)
+		return nil
+	}
+	var packages []string
+	for _, pattern := range patterns {
+		if err := w.walk(pattern, add, &packages); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.walkPackages(packages, add); err != nil {
+		return nil, err
+	}
+
+	out := files.asSlice()
+	sort.Strings(out)
+	return out, nil
+}
+
+// walk walks a file or directory pattern, patterns that are not on disk are appended to packages.
+func (w *Walker) walk(pattern string, fn func(path string) error, packages *[]string) error {
+	root := strings.TrimSuffix(pattern, "/...")
+	var fi fs.FileInfo
+	var err error
+	if w.FS == nil {
+		fi, err = os.Stat(root)
+		if err != nil {
+			// not a path on disk, let go list resolve the package pattern
+			*packages = append(*packages, pattern)
+			return nil
+		}
+	} else {
+		root = path.Clean(root)
+		fi, err = fs.Stat(w.FS, root)
+		if err != nil {
+			return fmt.Errorf("unable to resolve `%s': %w", pattern, err)
+		}
+	}
+
+	t, name, err := w.newWalk(root)
+	if err != nil {
+		return err
+	}
+	t.abs = filepath.IsAbs(root)
+	t.relative = !t.abs
+	if fi.IsDir() {
+		return t.walkDir(name, root != pattern, fn)
+	}
+	return t.walkFile(name, fn)
+}
+
+// walkPackages calls fn for the files of the package patterns.
+func (w *Walker) walkPackages(packages []string, fn func(path string) error) error {
+	if len(packages) == 0 {
+		return nil
+	}
+	listed, err := w.goList(packages)
+	if err != nil {
+		return err
+	}
+	for _, file := range listed {
+		t, name, err := w.newWalk(file)
+		if err != nil {
+			return err
+		}
+		if err := t.walkFile(name, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Walker) debugf(format string, args ...interface{}) {
+	if w.Debugf != nil {
+		w.Debugf(format, args...)
+	}
+}
+
+func (w *Walker) warnf(format string, args ...interface{}) {
+	if w.Warnf != nil {
+		w.Warnf(format, args...)
+	}
+}
+
+// walk is the state of a single walk. All paths are handled as slash separated names of fsys,
+// for the file system of the operating system fsys is the root of the volume of the walked path.
+type walk struct {
+	w      *Walker
+	fsys   fs.FS
+	native bool
+	volume string
+	wd     string
+	// abs and relative are set if an absolute or a relative path is walked, the paths passed
+	// to the callback are absolute or relative as well
+	abs      bool
+	relative bool
+	ignores  *ignoreMatcher
+}
+
+// newWalk returns the walk for the path p and the name of p in the walked file system.
+func (w *Walker) newWalk(p string) (*walk, string, error) {
+	t := &walk{w: w, fsys: w.FS}
+	name := p
+	if w.FS == nil {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return nil, "", fmt.Errorf("unable to resolve `%s': %w", p, err)
+		}
+		t.native = true
+		t.volume = filepath.VolumeName(abs)
+		t.wd, _ = os.Getwd()
+		t.fsys = os.DirFS(t.volume + string(filepath.Separator))
+		name = nativeName(abs[len(t.volume):])
+	}
+	if w.IgnoreFiles {
+		t.ignores = newIgnoreMatcher(t.fsys)
+	}
+	return t, name, nil
+}
+
+// nativeName converts an absolute file path without volume to a name of the volume's file system.
+func nativeName(p string) string {
+	name := strings.TrimLeft(filepath.ToSlash(p), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+// path converts name back into a file path. Without an explicit style it is relative
+// to the working directory if name is inside the working directory.
+func (t *walk) path(name string) string {
+	if !t.native {
+		return name
+	}
+	abs := filepath.Join(t.volume+string(filepath.Separator), filepath.FromSlash(name))
+	if t.abs || t.wd == "" {
+		return abs
+	}
+	rel, err := filepath.Rel(t.wd, abs)
+	if err != nil || (!t.relative && strings.HasPrefix(rel, "..")) {
+		return abs
+	}
+	if strings.HasPrefix(rel, ".") {
+		return rel
+	}
+	// package names must start with a ./
+	return "./" + rel
+}
+
+// resolve returns the name of the path p, relative paths are relative to the directory dir.
+// It reports false if p is not part of the walked file system.
+func (t *walk) resolve(dir, p string) (string, bool) {
+	if t.native && filepath.IsAbs(p) {
+		if filepath.VolumeName(p) != t.volume {
+			return "", false
+		}
+		return nativeName(p[len(t.volume):]), true
+	}
+	name := path.Join(dir, filepath.ToSlash(p))
+	return name, fs.ValidPath(name)
+}
+
+// workingDir returns the name of the working directory, patterns of filters are relative to it for single files.
+func (t *walk) workingDir() string {
+	if !t.native || t.wd == "" {
+		return "."
+	}
+	if name, ok := t.resolve(".", t.wd); ok {
+		return name
+	}
+	return "."
+}
+
+// dirFilter is the resolved Filter of a directory.
+type dirFilter struct {
+	skip       map[string]bool
+	skipHidden bool
+	include    []pathPattern
+	exclude    []pathPattern
+}
+
+// filter returns the filter for the entries of the directory dir, patterns without Dir are relative to root.
+func (t *walk) filter(dir, root string) (dirFilter, error) {
+	f := t.w.Filter
+	if t.w.FilterFor != nil {
+		var err error
+		f, err = t.w.FilterFor(t.path(dir))
+		if err != nil {
+			return dirFilter{}, err
+		}
+	}
+
+	result := dirFilter{
+		skip:       make(map[string]bool, len(f.Skip)),
+		skipHidden: f.SkipHidden,
+		include:    t.patterns(f.Include, root),
+		exclude:    t.patterns(f.Exclude, root),
+	}
+	for _, name := range f.Skip {
+		result.skip[name] = true
+	}
+	return result, nil
+}
+
+func (t *walk) patterns(patterns []Pattern, root string) []pathPattern {
+	result := make([]pathPattern, 0, len(patterns))
+	for _, p := range patterns {
+		base := root
+		if p.Dir != "" {
+			var ok bool
+			if t.native {
+				abs, err := filepath.Abs(p.Dir)
+				if err != nil {
+					continue
+				}
+				base, ok = t.resolve(".", abs)
+			} else {
+				base, ok = t.resolve(".", p.Dir)
+			}
+			if !ok {
+				continue
+			}
+		}
+		result = append(result, pathPattern{base: base, pattern: p.Glob})
+	}
+	return result
+}
+
+// skipped reports whether the entry name is skipped by name or because it is hidden.
+func (f dirFilter) skipped(name, p string) bool {
+	base := path.Base(name)
+	if f.skip[base] || f.skip[p] || f.skip[filepath.Clean(p)] {
+		return true
+	}
+	return f.skipHidden && base != "." && base != ".." && strings.ContainsAny(base[0:1], "_.")
+}
+
+// walkDir calls fn for the go files in root (and its subdirectories if recursive is set).
+func (t *walk) walkDir(root string, recursive bool, fn func(path string) error) error {
+	workspace, err := t.workspaceModules(root)
+	if err != nil {
+		return err
+	}
+	return fs.WalkDir(t.fsys, root, func(name string, d fs.DirEntry, err error) error {
+		p := t.path(name)
+		if err != nil {
+			return fmt.Errorf("unable to walk `%s': %w", p, err)
+		}
+
+		f, err := t.filter(path.Dir(name), root)
+		if err != nil {
+			return err
+		}
+		excluded := matchAny(f.exclude, name)
+		included := matchAny(f.include, name)
+		skip := f.skipped(name, p)
+		if name != root {
+			ignored, err := t.ignores.match(name, d.IsDir())
+			if err != nil {
+				return err
+			}
+			skip = skip || ignored
+		}
+		if d.IsDir() && name != root {
+			if !recursive {
+				return fs.SkipDir
+			}
+			base := path.Base(name)
+			skip = skip || base == "testdata" || base == "vendor" || (t.isModuleRoot(name) && !workspace[name])
+		}
+		switch {
+		case d.IsDir() && (excluded || (skip && !included && !matchAnyBelow(f.include, name))):
+			return fs.SkipDir
+		case d.IsDir():
+		case !strings.HasSuffix(name, ".go") || (skip && !included):
+		case excluded || (len(f.include) > 0 && !included):
+			t.w.debugf("skipping %s", p)
+		case len(t.w.Tags) > 0 && !t.matchBuildTags(name):
+			t.w.debugf("skipping %s (build constraints)", p)
+		default:
+			return fn(p)
+		}
+		return nil
+	})
+}
+
+// walkFile calls fn for a file that was passed explicitly or resolved by go list,
+// patterns without Dir are relative to the working directory.
+func (t *walk) walkFile(name string, fn func(path string) error) error {
+	p := t.path(name)
+	f, err := t.filter(path.Dir(name), t.workingDir())
+	if err != nil {
+		return err
+	}
+	if matchAny(f.exclude, name) || (len(f.include) > 0 && !matchAny(f.include, name)) {
+		t.w.debugf("skipping %s", p)
+		return nil
+	}
+	return fn(p)
+}
+
+// relName returns name relative to the directory base, it reports false if name is not inside base.
+func relName(base, name string) (string, bool) {
+	switch {
+	case base == ".":
+		return name, true
+	case name == base:
+		return ".", true
+	case strings.HasPrefix(name, base+"/"):
+		return name[len(base)+1:], true
+	}
+	return "", false
+}
@@ -0,0 +1,176 @@
+package walker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testFS() fstest.MapFS {
+	file := &fstest.MapFile{Data: []byte("package a\n")}
+	return fstest.MapFS{
+		"go.mod":                       file,
+		"a.go":                         file,
+		"a.txt":                        file,
+		"_hidden/a.go":                 file,
+		".git/info/exclude":            &fstest.MapFile{Data: []byte("excluded.go\n")},
+		".gitignore":                   &fstest.MapFile{Data: []byte("ignored/\n")},
+		"excluded.go":                  file,
+		"ignored/a.go":                 file,
+		"sub/b.go":                     file,
+		"sub/b_mock.go":                file,
+		"sub/testdata/a.go":            file,
+		"sub/vendor/a.go":              file,
+		"sub/deep/c.go":                file,
+		"module/go.mod":                file,
+		"module/d.go":                  file,
+		"tagged/ignore.go":             &fstest.MapFile{Data: []byte("//go:build ignore\n\npackage a\n")},
+		"tagged/never.go":              &fstest.MapFile{Data: []byte("//go:build never\n\npackage a\n")},
+		"workspace/go.work":            &fstest.MapFile{Data: []byte("go 1.20\n\nuse ./used\n")},
+		"workspace/used/go.mod":        file,
+		"workspace/used/e.go":          file,
+		"workspace/unused/go.mod":      file,
+		"workspace/unused/f.go":        file,
+		"workspace/.hidden/skipped.go": file,
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		setup    func(w *Walker)
+		expected []string
+	}{
+		{
+			[]string{"./..."},
+			nil,
+			[]string{"a.go", "sub/b.go", "sub/b_mock.go", "sub/deep/c.go", "tagged/ignore.go", "tagged/never.go"},
+		},
+		{
+			[]string{"sub"},
+			nil,
+			[]string{"sub/b.go", "sub/b_mock.go"},
+		},
+		{
+			[]string{"sub/...", "sub/b.go", "module/d.go"},
+			nil,
+			[]string{"module/d.go", "sub/b.go", "sub/b_mock.go", "sub/deep/c.go"},
+		},
+		{
+			[]string{"workspace/..."},
+			nil,
+			[]string{"workspace/used/e.go"},
+		},
+		{
+			[]string{"sub/..."},
+			func(w *Walker) {
+				w.Filter.Skip = []string{"deep"}
+				w.Filter.Exclude = []Pattern{{Glob: "**/*_mock.go"}}
+			},
+			[]string{"sub/b.go"},
+		},
+		{
+			[]string{"./..."},
+			func(w *Walker) {
+				w.Filter.Include = []Pattern{{Dir: "sub", Glob: "**/c.go"}, {Glob: "_hidden/*.go"}}
+			},
+			[]string{"_hidden/a.go", "sub/deep/c.go"},
+		},
+		{
+			[]string{"./..."},
+			func(w *Walker) {
+				w.Filter.SkipHidden = false
+				w.IgnoreFiles = false
+			},
+			[]string{
+				"_hidden/a.go", "a.go", "excluded.go", "ignored/a.go", "sub/b.go", "sub/b_mock.go",
+				"sub/deep/c.go", "tagged/ignore.go", "tagged/never.go", "workspace/.hidden/skipped.go",
+			},
+		},
+		{
+			[]string{"tagged"},
+			func(w *Walker) {
+				w.Tags = []string{"never"}
+			},
+			[]string{"tagged/never.go"},
+		},
+		{
+			[]string{"./..."},
+			func(w *Walker) {
+				w.FilterFor = func(dir string) (Filter, error) {
+					if dir == "sub" {
+						return Filter{Exclude: []Pattern{{Dir: "sub", Glob: "b.go"}}}, nil
+					}
+					return Filter{SkipHidden: true, Skip: []string{"tagged"}}, nil
+				}
+			},
+			[]string{"a.go", "sub/b_mock.go", "sub/deep/c.go"},
+		},
+	}
+
+	for i, test := range tests {
+		w := New()
+		w.FS = testFS()
+		if test.setup != nil {
+			test.setup(w)
+		}
+		files, err := w.Resolve(test.patterns)
+		require.NoError(t, err, "Test %d failed", i)
+		require.Equal(t, test.expected, files, "Test %d failed", i)
+	}
+}
+
+func TestResolveNotExisting(t *testing.T) {
+	w := New()
+	w.FS = testFS()
+	_, err := w.Resolve([]string{"missing/..."})
+	require.Error(t, err)
+}
+
+func TestWalkStop(t *testing.T) {
+	w := New()
+	w.FS = testFS()
+	var files []string
+	err := w.Walk("./...", func(path string) error {
+		files = append(files, path)
+		if len(files) == 2 {
+			return os.ErrClosed
+		}
+		return nil
+	})
+	require.ErrorIs(t, err, os.ErrClosed)
+	require.Equal(t, []string{"a.go", "sub/b.go"}, files)
+}
+
+func TestWalkOS(t *testing.T) {
+	dir := t.TempDir()
+	for name, file := range testFS() {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, file.Data, 0o600))
+	}
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer func() {
+		require.NoError(t, os.Chdir(wd))
+	}()
+
+	w := New()
+	w.Filter.Skip = []string{"tagged"}
+	files, err := w.Resolve([]string{"./...", filepath.Join(dir, "workspace", "...")})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"./a.go", "./sub/b.go", "./sub/b_mock.go", "./sub/deep/c.go",
+		filepath.Join(dir, "workspace", "used", "e.go"),
+	}, files)
+
+	require.NoError(t, os.Chdir(filepath.Join(dir, "sub")))
+	files, err = New().Resolve([]string{"../module"})
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join("..", "module", "d.go")}, files)
+}